@@ -3,6 +3,9 @@ package config
 import (
 	"errors"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +17,13 @@ type AppConfig struct {
 	TwillioAccountSid      string
 	TwillioAuthToken       string
 	TwillioFromPhoneNumber string
+	StorageDir             string
+	StorageBaseUrl         string
+	PrivateStorageDir      string // files only handed out through the api
+	ReservationWindow      time.Duration
+	Currency               string
+	AbandonedCartAfter     time.Duration
+	PaymentSandbox         bool // approve every payment, for development only
 }
 
 func SetupEnv() (cfg AppConfig, err error) {
@@ -52,6 +62,43 @@ func SetupEnv() (cfg AppConfig, err error) {
 		return AppConfig{}, errors.New("env variables not found")
 	}
 
+	// uploaded files are kept on the local disk unless configured otherwise
+	storageDir := os.Getenv("STORAGE_DIR")
+	if len(storageDir) < 1 {
+		storageDir = "./uploads"
+	}
+
+	storageBaseUrl := os.Getenv("STORAGE_BASE_URL")
+	if len(storageBaseUrl) < 1 {
+		storageBaseUrl = "/uploads"
+	}
+
+	// invoices, documents and return photos, must not be inside the storage dir
+	privateStorageDir := os.Getenv("PRIVATE_STORAGE_DIR")
+	if len(privateStorageDir) < 1 {
+		privateStorageDir = "./private"
+	}
+
+	// how long stock stays reserved for an unpaid order
+	reservationWindow := 15 * time.Minute
+	if minutes, err := strconv.Atoi(os.Getenv("RESERVATION_MINUTES")); err == nil && minutes > 0 {
+		reservationWindow = time.Duration(minutes) * time.Minute
+	}
+
+	// prices are kept in this ISO 4217 currency
+	currency := strings.ToUpper(os.Getenv("CURRENCY"))
+	if len(currency) != 3 {
+		currency = "USD"
+	}
+
+	// carts untouched this long get a reminder
+	abandonedCartAfter := 24 * time.Hour
+	if hours, err := strconv.Atoi(os.Getenv("ABANDONED_CART_HOURS")); err == nil && hours > 0 {
+		abandonedCartAfter = time.Duration(hours) * time.Hour
+	}
+
+	paymentSandbox, _ := strconv.ParseBool(os.Getenv("PAYMENT_SANDBOX"))
+
 	return AppConfig{
 		ServerPort:             httpPort,
 		Dsn:                    dsn,
@@ -59,5 +106,12 @@ func SetupEnv() (cfg AppConfig, err error) {
 		TwillioAccountSid:      twillioAccountSid,
 		TwillioAuthToken:       twillioAuthToken,
 		TwillioFromPhoneNumber: twillioFromPhoneNumber,
+		StorageDir:             storageDir,
+		StorageBaseUrl:         storageBaseUrl,
+		PrivateStorageDir:      privateStorageDir,
+		ReservationWindow:      reservationWindow,
+		Currency:               currency,
+		AbandonedCartAfter:     abandonedCartAfter,
+		PaymentSandbox:         paymentSandbox,
 	}, nil
 }
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"log"
+	"time"
+)
+
+// guest carts are kept this long after they were last changed
+const guestCartLifetime = 30 * 24 * time.Hour
+
+// startJobs runs the background jobs of the api for as long as the process lives
+func startJobs(rh *rest.RestHandler) {
+	inventoryRepo := repository.NewInventoryRepository(rh.DB)
+	catalogSvc := service.CatalogService{
+		Repo:   repository.NewCatalogRepository(rh.DB),
+		Config: rh.Config,
+	}
+	userRepo := repository.NewUserRepository(rh.DB)
+	userSvc := service.UserService{
+		Repo:     userRepo,
+		CRepo:    repository.NewCatalogRepository(rh.DB),
+		Wishlist: repository.NewWishlistRepository(rh.DB),
+		Config:   rh.Config,
+	}
+	cartReminderSvc := service.CartReminderService{
+		Repo:     repository.NewCartReminderRepository(rh.DB),
+		UserRepo: userRepo,
+		Config:   rh.Config,
+	}
+	notificationSvc := service.NotificationService{
+		Repo:   repository.NewNotificationRepository(rh.DB),
+		URepo:  repository.NewUserRepository(rh.DB),
+		Config: rh.Config,
+	}
+
+	go every(time.Minute, func() {
+		expired, err := inventoryRepo.ReleaseExpiredReservations(time.Now())
+		if err != nil {
+			log.Println("reservation sweeper error:", err)
+			return
+		}
+		if expired > 0 {
+			log.Printf("released stock of %v expired orders", expired)
+		}
+	})
+
+	go every(time.Minute, func() {
+		published, err := catalogSvc.PublishScheduledProducts(time.Now())
+		if err != nil {
+			log.Println("scheduled publish error:", err)
+			return
+		}
+		if published > 0 {
+			log.Printf("published %v scheduled products", published)
+		}
+	})
+
+	go every(10*time.Minute, func() {
+		notified, err := userSvc.NotifyWishlists()
+		if err != nil {
+			log.Println("wishlist alert error:", err)
+			return
+		}
+		if notified > 0 {
+			log.Printf("queued %v wishlist alerts", notified)
+		}
+	})
+
+	go every(15*time.Minute, func() {
+		sent, err := cartReminderSvc.SendReminders(time.Now())
+		if err != nil {
+			log.Println("cart reminder error:", err)
+			return
+		}
+		if sent > 0 {
+			log.Printf("queued %v abandoned cart reminders", sent)
+		}
+	})
+
+	go every(time.Hour, func() {
+		deleted, err := userRepo.DeleteStaleGuestCarts(time.Now().Add(-guestCartLifetime))
+		if err != nil {
+			log.Println("guest cart cleanup error:", err)
+			return
+		}
+		if deleted > 0 {
+			log.Printf("deleted %v items of abandoned guest carts", deleted)
+		}
+	})
+
+	go every(30*time.Second, func() {
+		if err := notificationSvc.DeliverPending(); err != nil {
+			log.Println("notification delivery error:", err)
+		}
+	})
+}
+
+func every(interval time.Duration, job func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job()
+		<-ticker.C
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"math"
+
+	"gorm.io/gorm"
+)
+
+// float amount columns replaced by domain.Money, each one is now stored as
+// <column>_amount in minor units and <column>_currency
+var legacyMoneyColumns = map[string][]string{
+	"products":           {"price", "sale_price"},
+	"product_variants":   {"price"},
+	"price_histories":    {"price"},
+	"carts":              {"price"},
+	"orders":             {"subtotal", "discount", "amount"},
+	"order_items":        {"price", "discount"},
+	"coupons":            {"min_order"},
+	"coupon_redemptions": {"amount"},
+}
+
+// migrateMoneyColumns moves amounts left in the old float columns to the
+// money columns created by AutoMigrate, then drops the old columns. Old
+// amounts are taken to be in the configured currency.
+func migrateMoneyColumns(db *gorm.DB, currency string) error {
+	scale := math.Pow10(domain.MinorUnits(currency))
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for table, columns := range legacyMoneyColumns {
+			for _, column := range columns {
+				if !tx.Migrator().HasColumn(table, column) {
+					continue
+				}
+
+				err := tx.Exec(fmt.Sprintf("UPDATE %v SET %v_amount = ROUND(COALESCE(%v, 0) * ?), %v_currency = ?", table, column, column, column), scale, currency).Error
+				if err != nil {
+					return err
+				}
+
+				err = tx.Migrator().DropColumn(table, column)
+				if err != nil {
+					return err
+				}
+				log.Printf("migrated %v.%v to %v minor units", table, column, currency)
+			}
+		}
+
+		// coupons kept the percent and the fixed amount in one value column
+		if tx.Migrator().HasColumn("coupons", "value") {
+			err := tx.Exec("UPDATE coupons SET percent = value WHERE type = ?", domain.CouponPercentage).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Exec("UPDATE coupons SET amount_amount = ROUND(value * ?), amount_currency = ? WHERE type = ?", scale, currency, domain.CouponFixed).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Migrator().DropColumn("coupons", "value")
+			if err != nil {
+				return err
+			}
+			log.Printf("migrated coupons.value to percent and %v minor units", currency)
+		}
+
+		return nil
+	})
+}
+
+// backfillShipments gives orders placed before orders were split by seller
+// one shipment per seller, in the status of the order
+func backfillShipments(db *gorm.DB) error {
+	result := db.Exec(`
+		INSERT INTO shipments (order_id, seller_id, status, weight,
+			subtotal_amount, subtotal_currency, discount_amount, discount_currency,
+			tax_amount, tax_currency, shipping_price_amount, shipping_price_currency,
+			amount_amount, amount_currency, created_at, updated_at)
+		SELECT oi.order_id, oi.seller_id, o.status, SUM(oi.weight * oi.qty),
+			SUM(oi.price_amount * oi.qty), o.subtotal_currency, SUM(oi.discount_amount), o.subtotal_currency,
+			SUM(oi.tax_amount), o.subtotal_currency, 0, o.subtotal_currency,
+			SUM(oi.price_amount * oi.qty - oi.discount_amount + COALESCE(t.exclusive, 0)), o.subtotal_currency,
+			o.created_at, NOW()
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		LEFT JOIN (
+			SELECT order_item_id, SUM(amount_amount) AS exclusive
+			FROM order_item_taxes WHERE NOT inclusive GROUP BY order_item_id
+		) t ON t.order_item_id = oi.id
+		WHERE NOT EXISTS (SELECT 1 FROM shipments s WHERE s.order_id = oi.order_id)
+		GROUP BY oi.order_id, oi.seller_id, o.status, o.subtotal_currency, o.created_at`)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("created %v shipments for existing orders", result.RowsAffected)
+	}
+
+	return nil
+}
+
+// backfillSellerBalance credits sellers with the shipments paid before
+// seller balances were kept
+func backfillSellerBalance(db *gorm.DB) error {
+	result := db.Exec(`
+		INSERT INTO balance_entries (seller_id, type, order_id, shipment_id, amount_amount, amount_currency, created_at)
+		SELECT s.seller_id, ?, s.order_id, s.id, s.amount_amount, s.amount_currency, s.created_at
+		FROM shipments s
+		WHERE s.status IN ? AND NOT EXISTS (SELECT 1 FROM balance_entries b WHERE b.shipment_id = s.id)`,
+		domain.BalanceSale, []string{domain.OrderPaid, domain.OrderShipped, domain.OrderDelivered})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("credited sellers with %v paid shipments", result.RowsAffected)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CartReminderHandler struct {
+	svc service.CartReminderService
+}
+
+func SetupCartReminderRoutes(rh *rest.RestHandler) {
+	// create in instance of cart reminder service and inject to handler
+	svc := service.CartReminderService{
+		Repo:     repository.NewCartReminderRepository(rh.DB),
+		UserRepo: repository.NewUserRepository(rh.DB),
+		Auth:     rh.Auth,
+		Config:   rh.Config,
+	}
+	handler := CartReminderHandler{
+		svc: svc,
+	}
+
+	// Buyers
+	buyerRoutes := rh.UserRoutes()
+	buyerRoutes.Put("/cart-reminders", handler.SetCartReminders)
+
+	// Sellers
+	selRoutes := rh.SellerRoutes()
+	selRoutes.Get("/reports/abandoned-carts", handler.GetSellerReport)
+
+	// Admin
+	adminRoutes := rh.AdminRoutes()
+	adminRoutes.Get("/reports/abandoned-carts", handler.GetReport)
+}
+
+func (h *CartReminderHandler) SetCartReminders(ctx *fiber.Ctx) error {
+	req := dto.CartRemindersRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "please provide valid inputs")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	err := h.svc.SetCartReminders(req, user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "cart reminders updated", &fiber.Map{
+		"cart_reminders": req.Enabled,
+	})
+}
+
+func (h *CartReminderHandler) GetSellerReport(ctx *fiber.Ctx) error {
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	// /seller/reports/abandoned-carts?days=30
+	report, err := h.svc.GetRecoveryReport(ctx.QueryInt("days", 30), user.ID)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "abandoned cart report", report)
+}
+
+func (h *CartReminderHandler) GetReport(ctx *fiber.Ctx) error {
+	report, err := h.svc.GetRecoveryReport(ctx.QueryInt("days", 30), 0)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "abandoned cart report", report)
+}
@@ -1,11 +1,12 @@
 package handlers
 
 import (
+	"bytes"
 	"go-ecommerce-app/internal/api/rest"
-	"go-ecommerce-app/internal/domain"
 	"go-ecommerce-app/internal/dto"
 	"go-ecommerce-app/internal/repository"
 	"go-ecommerce-app/internal/service"
+	"go-ecommerce-app/pkg/storage"
 	"net/http"
 	"strconv"
 
@@ -21,9 +22,12 @@ func SetupCatalogRoutes(rh *rest.RestHandler) {
 
 	// create in instance of user service and inject to handler
 	svc := service.CatalogService{
-		Repo:   repository.NewCatalogRepository(rh.DB),
-		Auth:   rh.Auth,
-		Config: rh.Config,
+		Repo:    repository.NewCatalogRepository(rh.DB),
+		IRepo:   repository.NewInventoryRepository(rh.DB),
+		Rates:   repository.NewExchangeRateRepository(rh.DB),
+		Auth:    rh.Auth,
+		Config:  rh.Config,
+		Storage: storage.NewLocalStorage(rh.Config),
 	}
 	handler := CatalogHandler{
 		svc: svc,
@@ -36,18 +40,44 @@ func SetupCatalogRoutes(rh *rest.RestHandler) {
 	app.Get("/categories/:id", handler.GetCategory)
 
 	// Private - manage Products and categories
-	selRoutes := app.Group("/seller", rh.Auth.AuthorizeSeller)
+	selRoutes := rh.SellerRoutes()
 	selRoutes.Post("/categories", handler.CreateCategories)
 	selRoutes.Patch("/categories/:id", handler.EditCategory)
 	selRoutes.Delete("/categories/:id", handler.DeleteCategory)
+	selRoutes.Post("/categories/:id/image", handler.UploadCategoryImage)
 
 	selRoutes.Get("/products", handler.GetSellerProducts)
-	selRoutes.Get("/products/:id", handler.GetProduct)
+	selRoutes.Get("/products/export", handler.ExportProducts)
+	selRoutes.Get("/products/low-stock", handler.GetLowStockProducts)
+	selRoutes.Post("/products/import", handler.ImportProducts)
+	selRoutes.Get("/products/:id", handler.GetSellerProductById)
 	selRoutes.Post("/products", handler.CreateProducts)
 	selRoutes.Patch("/products/:id", handler.UpdateStock)
 	selRoutes.Put("/products/:id", handler.EditProduct)
 	selRoutes.Delete("/products/:id", handler.DeleteProduct)
+	selRoutes.Put("/products/:id/status", handler.SetProductStatus)
+	selRoutes.Post("/products/:id/archive", handler.ArchiveProduct)
+	selRoutes.Post("/products/:id/unarchive", handler.UnarchiveProduct)
 
+	selRoutes.Get("/products/:id/movements", handler.GetStockMovements)
+	selRoutes.Put("/products/:id/reorder-threshold", handler.SetReorderThreshold)
+
+	selRoutes.Put("/products/:id/sale", handler.SetProductSale)
+	selRoutes.Delete("/products/:id/sale", handler.RemoveProductSale)
+	selRoutes.Get("/products/:id/price-history", handler.GetPriceHistory)
+
+	selRoutes.Put("/products/:id/options", handler.SetProductOptions)
+	selRoutes.Post("/products/:id/variants", handler.CreateVariant)
+	selRoutes.Put("/products/:id/variants/:variantId", handler.EditVariant)
+	selRoutes.Delete("/products/:id/variants/:variantId", handler.DeleteVariant)
+
+	selRoutes.Post("/products/:id/images", handler.UploadProductImages)
+	selRoutes.Put("/products/:id/images", handler.ReorderProductImages)
+	selRoutes.Delete("/products/:id/images/:imageId", handler.DeleteProductImage)
+
+	// Admin - catalog maintenance
+	adminRoutes := rh.AdminRoutes()
+	adminRoutes.Post("/catalog/purge", handler.PurgeCatalog)
 }
 
 func (h *CatalogHandler) GetCategories(ctx *fiber.Ctx) error {
@@ -63,11 +93,30 @@ func (h *CatalogHandler) GetCategories(ctx *fiber.Ctx) error {
 func (h *CatalogHandler) GetCategory(ctx *fiber.Ctx) error {
 	id, _ := strconv.Atoi(ctx.Params("id"))
 
-	category, err := h.svc.GetCategory(id)
+	// products are only loaded when asked for: /categories/:id?products=true&page=1&limit=20
+	if !ctx.QueryBool("products") {
+		category, err := h.svc.GetCategory(id)
+		if err != nil {
+			return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+		}
+		return rest.SuccessResponse(ctx, "category", category)
+	}
+
+	var page dto.PaginationRequest
+	err := ctx.QueryParser(&page)
+	if err != nil {
+		return rest.BadRequestError(ctx, "pagination parameters are not valid")
+	}
+
+	category, pagination, err := h.svc.GetCategoryWithProducts(id, page, displayCurrency(ctx))
 	if err != nil {
 		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
 	}
-	return rest.SuccessResponse(ctx, "category", category)
+
+	return rest.SuccessResponse(ctx, "category", &fiber.Map{
+		"category":   category,
+		"pagination": pagination,
+	})
 }
 
 func (h *CatalogHandler) CreateCategories(ctx *fiber.Ctx) error {
@@ -135,7 +184,8 @@ func (h *CatalogHandler) CreateProducts(ctx *fiber.Ctx) error {
 
 func (h *CatalogHandler) GetProducts(ctx *fiber.Ctx) error {
 
-	products, err := h.svc.GetProducts()
+	// prices can be shown in another currency: /products?currency=EUR
+	products, err := h.svc.GetProducts(displayCurrency(ctx))
 	if err != nil {
 		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
 	}
@@ -159,7 +209,20 @@ func (h *CatalogHandler) GetProduct(ctx *fiber.Ctx) error {
 
 	id, _ := strconv.Atoi(ctx.Params("id"))
 
-	product, err := h.svc.Repo.FindProductById(id)
+	product, err := h.svc.GetProduct(id, displayCurrency(ctx))
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return rest.SuccessResponse(ctx, "product", product)
+}
+
+func (h *CatalogHandler) GetSellerProductById(ctx *fiber.Ctx) error {
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.GetSellerProductById(id, user)
 	if err != nil {
 		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
 	}
@@ -197,18 +260,111 @@ func (h *CatalogHandler) UpdateStock(ctx *fiber.Ctx) error {
 
 	user := h.svc.Auth.GetCurrentUser(ctx)
 
-	product := &domain.Product{
-		ID:     uint(id),
-		Stock:  uint(req.Stock),
-		UserId: int(user.ID),
+	updatedProduct, err := h.svc.UpdateProductStock(id, req, user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
 	}
 
-	updatedProduct, err := h.svc.UpdateProductStock(product)
+	return rest.SuccessResponse(ctx, "UpdateStock", updatedProduct)
+}
+
+func (h *CatalogHandler) SetReorderThreshold(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var req dto.ReorderThresholdRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "reorder threshold request is not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.SetReorderThreshold(id, req, user)
 	if err != nil {
 		return rest.InternalError(ctx, err)
 	}
 
-	return rest.SuccessResponse(ctx, "UpdateStock", updatedProduct)
+	return rest.SuccessResponse(ctx, "SetReorderThreshold", product)
+}
+
+func (h *CatalogHandler) SetProductSale(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var req dto.ProductSaleRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "sale request is not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.SetProductSale(id, req, user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "SetProductSale", product)
+}
+
+func (h *CatalogHandler) RemoveProductSale(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.RemoveProductSale(id, user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "RemoveProductSale", product)
+}
+
+func (h *CatalogHandler) GetPriceHistory(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	// /seller/products/:id/price-history?days=90
+	history, err := h.svc.GetPriceHistory(id, ctx.QueryInt("days"), user)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return rest.SuccessResponse(ctx, "GetPriceHistory", history)
+}
+
+func (h *CatalogHandler) GetLowStockProducts(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	products, err := h.svc.GetLowStockProducts(user)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return rest.SuccessResponse(ctx, "GetLowStockProducts", products)
+}
+
+func (h *CatalogHandler) GetStockMovements(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var page dto.PaginationRequest
+	err := ctx.QueryParser(&page)
+	if err != nil {
+		return rest.BadRequestError(ctx, "pagination parameters are not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	movements, pagination, err := h.svc.GetStockMovements(id, page, user)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return rest.SuccessResponse(ctx, "GetStockMovements", &fiber.Map{
+		"movements":  movements,
+		"pagination": pagination,
+	})
 }
 
 func (h *CatalogHandler) DeleteProduct(ctx *fiber.Ctx) error {
@@ -222,3 +378,244 @@ func (h *CatalogHandler) DeleteProduct(ctx *fiber.Ctx) error {
 	}
 	return rest.SuccessResponse(ctx, "DeleteProduct", nil)
 }
+
+func (h *CatalogHandler) SetProductOptions(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var req dto.SetProductOptionsRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "product options request is not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.SetProductOptions(id, req, user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "SetProductOptions", product)
+}
+
+func (h *CatalogHandler) CreateVariant(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var req dto.CreateVariantRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "create variant request is not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.CreateVariant(id, req, user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "CreateVariant", product)
+}
+
+func (h *CatalogHandler) EditVariant(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	variantId, _ := strconv.Atoi(ctx.Params("variantId"))
+
+	var req dto.CreateVariantRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "edit variant request is not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.EditVariant(id, variantId, req, user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "EditVariant", product)
+}
+
+func (h *CatalogHandler) DeleteVariant(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	variantId, _ := strconv.Atoi(ctx.Params("variantId"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.DeleteVariant(id, variantId, user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "DeleteVariant", product)
+}
+
+func (h *CatalogHandler) UploadProductImages(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return rest.BadRequestError(ctx, "please upload images as multipart form data")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.UploadProductImages(id, form.File["images"], user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "UploadProductImages", product)
+}
+
+func (h *CatalogHandler) ReorderProductImages(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var req dto.ReorderImagesRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "reorder images request is not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.ReorderProductImages(id, req, user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "ReorderProductImages", product)
+}
+
+func (h *CatalogHandler) DeleteProductImage(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	imageId, _ := strconv.Atoi(ctx.Params("imageId"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.DeleteProductImage(id, imageId, user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "DeleteProductImage", product)
+}
+
+func (h *CatalogHandler) UploadCategoryImage(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	file, err := ctx.FormFile("image")
+	if err != nil {
+		return rest.BadRequestError(ctx, "please upload an image as multipart form data")
+	}
+
+	category, err := h.svc.UploadCategoryImage(id, file)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "UploadCategoryImage", category)
+}
+
+func (h *CatalogHandler) ImportProducts(ctx *fiber.Ctx) error {
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		return rest.BadRequestError(ctx, "please upload a csv file as multipart form data")
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return rest.BadRequestError(ctx, "unable to read the csv file")
+	}
+	defer f.Close()
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	result, err := h.svc.ImportProducts(f, ctx.QueryBool("dry_run"), user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	if len(result.Errors) > 0 {
+		return ctx.Status(http.StatusUnprocessableEntity).JSON(&fiber.Map{
+			"message": "import has invalid rows, nothing was saved",
+			"data":    result,
+		})
+	}
+
+	return rest.SuccessResponse(ctx, "ImportProducts", result)
+}
+
+func (h *CatalogHandler) ExportProducts(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	var buf bytes.Buffer
+	err := h.svc.ExportProducts(&buf, user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/csv")
+	ctx.Attachment("products.csv")
+	return ctx.Status(http.StatusOK).Send(buf.Bytes())
+}
+
+func (h *CatalogHandler) SetProductStatus(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var req dto.ProductStatusRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "product status request is not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.SetProductStatus(id, req, user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "SetProductStatus", product)
+}
+
+func (h *CatalogHandler) ArchiveProduct(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.ArchiveProduct(id, user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "ArchiveProduct", product)
+}
+
+func (h *CatalogHandler) UnarchiveProduct(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	product, err := h.svc.UnarchiveProduct(id, user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "UnarchiveProduct", product)
+}
+
+func (h *CatalogHandler) PurgeCatalog(ctx *fiber.Ctx) error {
+	// rows deleted or archived for longer than this are removed, default 90 days
+	days := ctx.QueryInt("days", 90)
+
+	result, err := h.svc.PurgeCatalog(days)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "PurgeCatalog", result)
+}
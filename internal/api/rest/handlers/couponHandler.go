@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CouponHandler struct {
+	svc service.CouponService
+}
+
+func SetupCouponRoutes(rh *rest.RestHandler) {
+	// create in instance of coupon service and inject to handler
+	svc := service.CouponService{
+		Repo:   repository.NewCouponRepository(rh.DB),
+		Auth:   rh.Auth,
+		Config: rh.Config,
+	}
+	handler := CouponHandler{
+		svc: svc,
+	}
+
+	// Admins - manage coupons
+	adminRoutes := rh.AdminRoutes()
+	adminRoutes.Get("/coupons", handler.GetCoupons)
+	adminRoutes.Post("/coupons", handler.CreateCoupon)
+	adminRoutes.Patch("/coupons/:id", handler.EditCoupon)
+}
+
+func (h *CouponHandler) GetCoupons(ctx *fiber.Ctx) error {
+
+	coupons, err := h.svc.GetCoupons()
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return rest.SuccessResponse(ctx, "coupons", coupons)
+}
+
+func (h *CouponHandler) CreateCoupon(ctx *fiber.Ctx) error {
+
+	var req dto.CreateCouponRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "create coupon request is not valid")
+	}
+
+	coupon, err := h.svc.CreateCoupon(req)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "CreateCoupon", coupon)
+}
+
+func (h *CouponHandler) EditCoupon(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var req dto.EditCouponRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "edit coupon request is not valid")
+	}
+
+	coupon, err := h.svc.EditCoupon(uint(id), req)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "EditCoupon", coupon)
+}
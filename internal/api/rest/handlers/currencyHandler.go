@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CurrencyHandler struct {
+	svc service.CurrencyService
+}
+
+func SetupCurrencyRoutes(rh *rest.RestHandler) {
+	app := rh.App
+
+	// create in instance of currency service and inject to handler
+	svc := service.CurrencyService{
+		Repo:   repository.NewExchangeRateRepository(rh.DB),
+		Auth:   rh.Auth,
+		Config: rh.Config,
+	}
+	handler := CurrencyHandler{
+		svc: svc,
+	}
+
+	// Public - rates used for display prices
+	app.Get("/exchange-rates", handler.GetRates)
+
+	// Admins - maintain rates
+	adminRoutes := rh.AdminRoutes()
+	adminRoutes.Put("/exchange-rates", handler.UpdateRates)
+	adminRoutes.Post("/exchange-rates/import", handler.ImportRates)
+	adminRoutes.Delete("/exchange-rates/:currency", handler.DeleteRate)
+}
+
+func (h *CurrencyHandler) GetRates(ctx *fiber.Ctx) error {
+
+	rates, err := h.svc.GetRates()
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return rest.SuccessResponse(ctx, "exchange rates", &fiber.Map{
+		"base":  h.svc.Config.Currency,
+		"rates": rates,
+	})
+}
+
+func (h *CurrencyHandler) UpdateRates(ctx *fiber.Ctx) error {
+
+	var req dto.UpdateExchangeRatesRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "exchange rates request is not valid")
+	}
+
+	rates, err := h.svc.UpdateRates(req)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "UpdateRates", rates)
+}
+
+func (h *CurrencyHandler) ImportRates(ctx *fiber.Ctx) error {
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		return rest.BadRequestError(ctx, "please upload a csv file as multipart form data")
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return rest.BadRequestError(ctx, "unable to read the csv file")
+	}
+	defer f.Close()
+
+	rates, err := h.svc.ImportRates(f)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "ImportRates", rates)
+}
+
+func (h *CurrencyHandler) DeleteRate(ctx *fiber.Ctx) error {
+
+	rates, err := h.svc.DeleteRate(ctx.Params("currency"))
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "DeleteRate", rates)
+}
+
+// displayCurrency is the currency the buyer wants to see prices in, from the
+// currency query parameter or the Accept-Currency header
+func displayCurrency(ctx *fiber.Ctx) string {
+	currency := ctx.Query("currency")
+	if len(currency) == 0 {
+		currency = ctx.Get("Accept-Currency")
+	}
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"go-ecommerce-app/pkg/storage"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type InvoiceHandler struct {
+	svc service.InvoiceService
+}
+
+// newInvoiceService keeps the pdfs out of the public uploads, they hold the
+// buyer's addresses
+func newInvoiceService(rh *rest.RestHandler) service.InvoiceService {
+	return service.InvoiceService{
+		Repo:     repository.NewInvoiceRepository(rh.DB),
+		UserRepo: repository.NewUserRepository(rh.DB),
+		Storage:  storage.NewPrivateStorage(rh.Config),
+		Auth:     rh.Auth,
+		Config:   rh.Config,
+	}
+}
+
+func SetupInvoiceRoutes(rh *rest.RestHandler) {
+	// create in instance of invoice service and inject to handler
+	handler := InvoiceHandler{
+		svc: newInvoiceService(rh),
+	}
+
+	// Buyers
+	buyerRoutes := rh.UserRoutes()
+	buyerRoutes.Get("/order/:id/invoices", handler.GetOrderInvoices)
+	buyerRoutes.Get("/invoices/:id/pdf", handler.DownloadInvoice)
+
+	// Sellers
+	selRoutes := rh.SellerRoutes()
+	selRoutes.Get("/invoices", handler.GetSellerInvoices)
+	selRoutes.Get("/invoices/:id/pdf", handler.DownloadSellerInvoice)
+}
+
+func (h *InvoiceHandler) GetOrderInvoices(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	invoices, err := h.svc.GetOrderInvoices(uint(id), user)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return rest.SuccessResponse(ctx, "invoices", invoices)
+}
+
+func (h *InvoiceHandler) GetSellerInvoices(ctx *fiber.Ctx) error {
+	var page dto.PaginationRequest
+	err := ctx.QueryParser(&page)
+	if err != nil {
+		return rest.BadRequestError(ctx, "pagination parameters are not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	invoices, pagination, err := h.svc.GetSellerInvoices(page, user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "invoices", &fiber.Map{
+		"invoices":   invoices,
+		"pagination": pagination,
+	})
+}
+
+func (h *InvoiceHandler) DownloadInvoice(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	invoice, data, err := h.svc.DownloadInvoice(uint(id), user)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return sendPdf(ctx, invoice, data)
+}
+
+func (h *InvoiceHandler) DownloadSellerInvoice(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	invoice, data, err := h.svc.DownloadSellerInvoice(uint(id), user)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return sendPdf(ctx, invoice, data)
+}
+
+func sendPdf(ctx *fiber.Ctx, invoice *domain.Invoice, data []byte) error {
+	ctx.Set(fiber.HeaderContentType, "application/pdf")
+	ctx.Attachment(invoice.FileName())
+	return ctx.Status(http.StatusOK).Send(data)
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"go-ecommerce-app/pkg/storage"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReturnHandler struct {
+	svc service.ReturnService
+}
+
+func SetupReturnRoutes(rh *rest.RestHandler) {
+	// create in instance of return service and inject to handler
+	svc := service.ReturnService{
+		Repo:     repository.NewReturnRepository(rh.DB),
+		UserRepo: repository.NewUserRepository(rh.DB),
+		Storage:  storage.NewPrivateStorage(rh.Config),
+		Auth:     rh.Auth,
+		Config:   rh.Config,
+	}
+	handler := ReturnHandler{
+		svc: svc,
+	}
+
+	// Buyers
+	buyerRoutes := rh.UserRoutes()
+	buyerRoutes.Post("/order/:id/returns", handler.CreateReturn)
+	buyerRoutes.Get("/returns", handler.GetUserReturns)
+	buyerRoutes.Get("/returns/:id/photos/:n", handler.GetUserReturnPhoto)
+
+	// Sellers
+	selRoutes := rh.SellerRoutes()
+	selRoutes.Get("/returns", handler.GetSellerReturns)
+	selRoutes.Get("/returns/:id/photos/:n", handler.GetSellerReturnPhoto)
+	selRoutes.Patch("/returns/:id", handler.ReviewReturn)
+	selRoutes.Post("/returns/:id/receive", handler.ReceiveReturn)
+	selRoutes.Post("/returns/:id/refund", handler.RefundReturn)
+}
+
+func (h *ReturnHandler) CreateReturn(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return rest.BadRequestError(ctx, "please send the return request as multipart form data")
+	}
+
+	var req dto.CreateReturnRequest
+	err = ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "return request is not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	e, err := h.svc.CreateReturn(uint(id), req, form.File["photos"], user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "CreateReturn", e)
+}
+
+func (h *ReturnHandler) GetUserReturns(ctx *fiber.Ctx) error {
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	returns, err := h.svc.GetUserReturns(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "returns", returns)
+}
+
+func (h *ReturnHandler) GetUserReturnPhoto(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	n, _ := strconv.Atoi(ctx.Params("n"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	data, err := h.svc.GetUserReturnPhoto(uint(id), n, user)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, http.DetectContentType(data))
+	return ctx.Status(http.StatusOK).Send(data)
+}
+
+func (h *ReturnHandler) GetSellerReturnPhoto(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	n, _ := strconv.Atoi(ctx.Params("n"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	data, err := h.svc.GetSellerReturnPhoto(uint(id), n, user)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, http.DetectContentType(data))
+	return ctx.Status(http.StatusOK).Send(data)
+}
+
+func (h *ReturnHandler) GetSellerReturns(ctx *fiber.Ctx) error {
+	var page dto.PaginationRequest
+	err := ctx.QueryParser(&page)
+	if err != nil {
+		return rest.BadRequestError(ctx, "pagination parameters are not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	returns, pagination, err := h.svc.GetSellerReturns(ctx.Query("status"), page, user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "returns", &fiber.Map{
+		"returns":    returns,
+		"pagination": pagination,
+	})
+}
+
+func (h *ReturnHandler) ReviewReturn(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var req dto.ReviewReturnRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "review return request is not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	e, err := h.svc.ReviewReturn(uint(id), req, user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "ReviewReturn", e)
+}
+
+func (h *ReturnHandler) ReceiveReturn(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	e, err := h.svc.ReceiveReturn(uint(id), user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "ReceiveReturn", e)
+}
+
+func (h *ReturnHandler) RefundReturn(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	e, err := h.svc.RefundReturn(uint(id), user)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusBadGateway, err)
+	}
+
+	return rest.SuccessResponse(ctx, "RefundReturn", e)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReviewHandler struct {
+	svc service.ReviewService
+}
+
+func SetupReviewRoutes(rh *rest.RestHandler) {
+	app := rh.App
+
+	// create in instance of review service and inject to handler
+	svc := service.ReviewService{
+		Repo:   repository.NewReviewRepository(rh.DB),
+		CRepo:  repository.NewCatalogRepository(rh.DB),
+		Auth:   rh.Auth,
+		Config: rh.Config,
+	}
+	handler := ReviewHandler{
+		svc: svc,
+	}
+
+	// Public
+	app.Get("/products/:id/reviews", handler.GetProductReviews)
+
+	// Buyers
+	app.Post("/products/:id/reviews", rh.Auth.Authorize, handler.CreateReview)
+
+	// Sellers
+	selRoutes := rh.SellerRoutes()
+	selRoutes.Post("/reviews/:id/reply", handler.ReplyToReview)
+
+	// Admins
+	adminRoutes := rh.AdminRoutes()
+	adminRoutes.Patch("/reviews/:id", handler.SetReviewHidden)
+}
+
+func (h *ReviewHandler) GetProductReviews(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var page dto.PaginationRequest
+	err := ctx.QueryParser(&page)
+	if err != nil {
+		return rest.BadRequestError(ctx, "pagination parameters are not valid")
+	}
+
+	reviews, pagination, err := h.svc.GetProductReviews(uint(id), page)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return rest.SuccessResponse(ctx, "reviews", &fiber.Map{
+		"reviews":    reviews,
+		"pagination": pagination,
+	})
+}
+
+func (h *ReviewHandler) CreateReview(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var req dto.CreateReviewRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "create review request is not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	review, err := h.svc.CreateReview(uint(id), req, user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "CreateReview", review)
+}
+
+func (h *ReviewHandler) ReplyToReview(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var req dto.ReviewReplyRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "review reply request is not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	review, err := h.svc.ReplyToReview(uint(id), req, user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "ReplyToReview", review)
+}
+
+func (h *ReviewHandler) SetReviewHidden(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var req dto.HideReviewRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "hide review request is not valid")
+	}
+
+	review, err := h.svc.SetReviewHidden(uint(id), req)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "SetReviewHidden", review)
+}
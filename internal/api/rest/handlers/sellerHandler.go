@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"go-ecommerce-app/pkg/storage"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SellerHandler struct {
+	svc service.SellerService
+}
+
+func SetupSellerRoutes(rh *rest.RestHandler) {
+	app := rh.App
+
+	// create in instance of seller service and inject to handler
+	svc := service.SellerService{
+		Repo:     repository.NewSellerRepository(rh.DB),
+		UserRepo: repository.NewUserRepository(rh.DB),
+		Catalog: service.CatalogService{
+			Repo:   repository.NewCatalogRepository(rh.DB),
+			Rates:  repository.NewExchangeRateRepository(rh.DB),
+			Config: rh.Config,
+		},
+		Rates:     repository.NewExchangeRateRepository(rh.DB),
+		Storage:   storage.NewLocalStorage(rh.Config),
+		Documents: storage.NewPrivateStorage(rh.Config),
+		Auth:      rh.Auth,
+		Config:    rh.Config,
+	}
+	handler := SellerHandler{
+		svc: svc,
+	}
+
+	// Public - storefronts
+	app.Get("/sellers/:id", handler.GetStorefront)
+	app.Get("/sellers/:id/products", handler.GetStorefrontProducts)
+
+	// Buyers - applying to the seller program
+	buyerRoutes := rh.UserRoutes()
+	buyerRoutes.Post("/become-seller", handler.BecomeSeller)
+	buyerRoutes.Get("/seller-application", handler.GetSellerApplication)
+
+	// Sellers
+	selRoutes := rh.SellerRoutes()
+	selRoutes.Get("/profile", handler.GetSellerProfile)
+	selRoutes.Put("/profile", handler.UpdateSellerProfile)
+	selRoutes.Post("/profile/logo", handler.UploadSellerLogo)
+
+	// Admin - reviewing seller applications
+	adminRoutes := rh.AdminRoutes()
+	adminRoutes.Get("/seller-applications", handler.GetSellerApplications)
+	adminRoutes.Get("/seller-applications/:id", handler.GetSellerApplicationById)
+	adminRoutes.Get("/seller-applications/:id/documents/:documentId", handler.DownloadSellerDocument)
+	adminRoutes.Post("/seller-applications/:id/approve", handler.ApproveSellerApplication)
+	adminRoutes.Post("/seller-applications/:id/reject", handler.RejectSellerApplication)
+}
+
+func (h *SellerHandler) GetStorefront(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	profile, err := h.svc.GetStorefront(uint(id))
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return rest.SuccessResponse(ctx, "seller", profile)
+}
+
+func (h *SellerHandler) GetStorefrontProducts(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var page dto.PaginationRequest
+	err := ctx.QueryParser(&page)
+	if err != nil {
+		return rest.BadRequestError(ctx, "pagination parameters are not valid")
+	}
+
+	// /sellers/:id/products?page=1&limit=20&currency=EUR
+	products, pagination, err := h.svc.GetStorefrontProducts(uint(id), page, displayCurrency(ctx))
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return rest.SuccessResponse(ctx, "seller products", &fiber.Map{
+		"products":   products,
+		"pagination": pagination,
+	})
+}
+
+func (h *SellerHandler) GetSellerProfile(ctx *fiber.Ctx) error {
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	profile, err := h.svc.GetSellerProfile(user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "seller profile", profile)
+}
+
+func (h *SellerHandler) UpdateSellerProfile(ctx *fiber.Ctx) error {
+	req := dto.SellerProfileRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "please provide valid inputs")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	profile, err := h.svc.UpdateSellerProfile(req, user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "seller profile updated", profile)
+}
+
+func (h *SellerHandler) UploadSellerLogo(ctx *fiber.Ctx) error {
+	file, err := ctx.FormFile("logo")
+	if err != nil {
+		return rest.BadRequestError(ctx, "please upload an image as multipart form data")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	profile, err := h.svc.UploadSellerLogo(file, user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "seller logo updated", profile)
+}
+
+func (h *SellerHandler) BecomeSeller(ctx *fiber.Ctx) error {
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return rest.BadRequestError(ctx, "please send the application as multipart form data")
+	}
+
+	req := dto.SellerInput{}
+	err = ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "request parameters are not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	application, err := h.svc.BecomeSeller(req, form.File, user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "seller application submitted, it will be reviewed shortly", application)
+}
+
+func (h *SellerHandler) GetSellerApplication(ctx *fiber.Ctx) error {
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	application, token, err := h.svc.GetSellerApplication(user)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	// the seller token is handed out once the application is approved
+	if len(token) > 0 {
+		return rest.SuccessResponse(ctx, "seller application", &fiber.Map{
+			"application": application,
+			"token":       token,
+		})
+	}
+
+	return rest.SuccessResponse(ctx, "seller application", &fiber.Map{
+		"application": application,
+	})
+}
+
+func (h *SellerHandler) GetSellerApplications(ctx *fiber.Ctx) error {
+	var page dto.PaginationRequest
+	err := ctx.QueryParser(&page)
+	if err != nil {
+		return rest.BadRequestError(ctx, "pagination parameters are not valid")
+	}
+
+	// /admin/seller-applications?status=pending
+	applications, pagination, err := h.svc.GetSellerApplications(ctx.Query("status"), page)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "seller applications", &fiber.Map{
+		"applications": applications,
+		"pagination":   pagination,
+	})
+}
+
+func (h *SellerHandler) GetSellerApplicationById(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	application, err := h.svc.GetSellerApplicationById(uint(id))
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return rest.SuccessResponse(ctx, "seller application", application)
+}
+
+func (h *SellerHandler) DownloadSellerDocument(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	documentId, _ := strconv.Atoi(ctx.Params("documentId"))
+
+	doc, data, err := h.svc.DownloadSellerDocument(uint(id), uint(documentId))
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, doc.ContentType)
+	ctx.Attachment(doc.FileName)
+	return ctx.Status(http.StatusOK).Send(data)
+}
+
+func (h *SellerHandler) ApproveSellerApplication(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	admin := h.svc.Auth.GetCurrentUser(ctx)
+
+	application, err := h.svc.ApproveSellerApplication(uint(id), admin)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "seller application approved", application)
+}
+
+func (h *SellerHandler) RejectSellerApplication(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	req := dto.ReviewSellerApplicationRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return rest.BadRequestError(ctx, "please provide valid inputs")
+	}
+
+	admin := h.svc.Auth.GetCurrentUser(ctx)
+
+	application, err := h.svc.RejectSellerApplication(uint(id), req, admin)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "seller application rejected", application)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ShippingHandler struct {
+	svc service.ShippingService
+}
+
+func SetupShippingRoutes(rh *rest.RestHandler) {
+	// create in instance of shipping service and inject to handler
+	svc := service.ShippingService{
+		Repo:   repository.NewShippingRepository(rh.DB),
+		Rates:  repository.NewExchangeRateRepository(rh.DB),
+		Auth:   rh.Auth,
+		Config: rh.Config,
+	}
+	handler := ShippingHandler{
+		svc: svc,
+	}
+
+	// Sellers - shipping methods they offer
+	selRoutes := rh.SellerRoutes()
+	selRoutes.Get("/shipping-profiles", handler.GetShippingProfiles)
+	selRoutes.Post("/shipping-profiles", handler.CreateShippingProfile)
+	selRoutes.Put("/shipping-profiles/:id", handler.EditShippingProfile)
+	selRoutes.Delete("/shipping-profiles/:id", handler.DeleteShippingProfile)
+}
+
+func (h *ShippingHandler) GetShippingProfiles(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	profiles, err := h.svc.GetShippingProfiles(user)
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return rest.SuccessResponse(ctx, "shipping profiles", profiles)
+}
+
+func (h *ShippingHandler) CreateShippingProfile(ctx *fiber.Ctx) error {
+
+	var req dto.ShippingProfileRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "create shipping profile request is not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	profile, err := h.svc.CreateShippingProfile(req, user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "shipping profile created successfully", profile)
+}
+
+func (h *ShippingHandler) EditShippingProfile(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var req dto.EditShippingProfileRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "edit shipping profile request is not valid")
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	profile, err := h.svc.EditShippingProfile(uint(id), req, user)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "shipping profile updated successfully", profile)
+}
+
+func (h *ShippingHandler) DeleteShippingProfile(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	err := h.svc.DeleteShippingProfile(uint(id), user)
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "shipping profile deleted successfully", id)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"go-ecommerce-app/internal/api/rest"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TaxHandler struct {
+	svc service.TaxService
+}
+
+func SetupTaxRoutes(rh *rest.RestHandler) {
+	// create in instance of tax service and inject to handler
+	svc := service.TaxService{
+		Repo:   repository.NewTaxRepository(rh.DB),
+		Auth:   rh.Auth,
+		Config: rh.Config,
+	}
+	handler := TaxHandler{
+		svc: svc,
+	}
+
+	// Admins - manage tax rules
+	adminRoutes := rh.AdminRoutes()
+	adminRoutes.Get("/tax-rules", handler.GetTaxRules)
+	adminRoutes.Post("/tax-rules", handler.CreateTaxRule)
+	adminRoutes.Patch("/tax-rules/:id", handler.EditTaxRule)
+	adminRoutes.Delete("/tax-rules/:id", handler.DeleteTaxRule)
+}
+
+func (h *TaxHandler) GetTaxRules(ctx *fiber.Ctx) error {
+
+	rules, err := h.svc.GetTaxRules()
+	if err != nil {
+		return rest.ErrorMessage(ctx, http.StatusNotFound, err)
+	}
+
+	return rest.SuccessResponse(ctx, "tax rules", rules)
+}
+
+func (h *TaxHandler) CreateTaxRule(ctx *fiber.Ctx) error {
+
+	var req dto.CreateTaxRuleRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "create tax rule request is not valid")
+	}
+
+	rule, err := h.svc.CreateTaxRule(req)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "tax rule created successfully", rule)
+}
+
+func (h *TaxHandler) EditTaxRule(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	var req dto.EditTaxRuleRequest
+	err := ctx.BodyParser(&req)
+	if err != nil {
+		return rest.BadRequestError(ctx, "edit tax rule request is not valid")
+	}
+
+	rule, err := h.svc.EditTaxRule(uint(id), req)
+	if err != nil {
+		return rest.BadRequestError(ctx, err.Error())
+	}
+
+	return rest.SuccessResponse(ctx, "tax rule updated successfully", rule)
+}
+
+func (h *TaxHandler) DeleteTaxRule(ctx *fiber.Ctx) error {
+	id, _ := strconv.Atoi(ctx.Params("id"))
+
+	err := h.svc.DeleteTaxRule(uint(id))
+	if err != nil {
+		return rest.InternalError(ctx, err)
+	}
+
+	return rest.SuccessResponse(ctx, "tax rule deleted successfully", id)
+}
@@ -6,10 +6,17 @@ import (
 	"go-ecommerce-app/internal/repository"
 	"go-ecommerce-app/internal/service"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
+// newGuestCartsPerHour is how many guest carts one client address can start
+// in an hour
+const newGuestCartsPerHour = 20
+
 type UserHandler struct {
 	svc service.UserService
 }
@@ -19,9 +26,17 @@ func SetupUserRoutes(rh *rest.RestHandler) {
 
 	// create in instance of user service and inject to handler
 	svc := service.UserService{
-		Repo:   repository.NewUserRepository(rh.DB),
-		Auth:   rh.Auth,
-		Config: rh.Config,
+		Repo:       repository.NewUserRepository(rh.DB),
+		CRepo:      repository.NewCatalogRepository(rh.DB),
+		CouponRepo: repository.NewCouponRepository(rh.DB),
+		Rates:      repository.NewExchangeRateRepository(rh.DB),
+		TaxRepo:    repository.NewTaxRepository(rh.DB),
+		ShipRepo:   repository.NewShippingRepository(rh.DB),
+		Balance:    repository.NewBalanceRepository(rh.DB),
+		Invoices:   newInvoiceService(rh),
+		Wishlist:   repository.NewWishlistRepository(rh.DB),
+		Auth:       rh.Auth,
+		Config:     rh.Config,
 	}
 	handler := UserHandler{
 		svc: svc,
@@ -33,7 +48,24 @@ func SetupUserRoutes(rh *rest.RestHandler) {
 	publicRoutes.Post("/register", handler.Register)
 	publicRoutes.Post("/login", handler.Login)
 
-	privateRoutes := publicRoutes.Group("/", rh.Auth.Authorize)
+	// Guests - carts identified by the X-Cart-Token header, merged on login
+	publicRoutes.Get("/guest-cart", handler.GetGuestCart)
+	publicRoutes.Post("/guest-cart", limiter.New(limiter.Config{
+		Max:        newGuestCartsPerHour,
+		Expiration: time.Hour,
+		// only requests that start a new cart are counted
+		Next: func(ctx *fiber.Ctx) bool {
+			_, err := rh.Auth.VerifyCartToken(cartToken(ctx, ""))
+			return err == nil
+		},
+		LimitReached: func(ctx *fiber.Ctx) error {
+			return ctx.Status(http.StatusTooManyRequests).JSON(&fiber.Map{
+				"message": "too many carts were started, please try again later",
+			})
+		},
+	}), handler.AddToGuestCart)
+
+	privateRoutes := rh.UserRoutes()
 
 	// Private endpoints
 	privateRoutes.Get("/verify", handler.GetVerificationCode)
@@ -42,13 +74,31 @@ func SetupUserRoutes(rh *rest.RestHandler) {
 	privateRoutes.Get("/profile", handler.GetProfile)
 	privateRoutes.Post("/profile", handler.CreateProfile)
 
+	privateRoutes.Get("/addresses", handler.GetAddresses)
+	privateRoutes.Post("/addresses", handler.CreateAddress)
+	privateRoutes.Delete("/addresses/:id", handler.DeleteAddress)
+
 	privateRoutes.Post("/cart", handler.AddToCart)
 	privateRoutes.Get("/cart", handler.GetCart)
+	privateRoutes.Post("/cart/coupon", handler.ApplyCoupon)
+	privateRoutes.Delete("/cart/coupon", handler.RemoveCoupon)
+	privateRoutes.Get("/cart/shipping-rates", handler.GetShippingRates)
+	privateRoutes.Post("/cart/save-for-later", handler.SaveForLater)
+	privateRoutes.Get("/wishlist", handler.GetWishlist)
+	privateRoutes.Post("/wishlist", handler.AddToWishlist)
+	privateRoutes.Delete("/wishlist/:id", handler.RemoveFromWishlist)
+	privateRoutes.Post("/wishlist/:id/move-to-cart", handler.MoveToCart)
 	privateRoutes.Post("/order", handler.CreateOrder)
 	privateRoutes.Get("/order", handler.GetOrders)
 	privateRoutes.Get("/order/:id", handler.GetOrder)
+	privateRoutes.Post("/order/:id/pay", handler.PayOrder)
+	privateRoutes.Post("/order/:id/cancel", handler.CancelOrder)
 
-	privateRoutes.Post("/become-seller", handler.BecomeSeller)
+	// Seller endpoints - fulfilment of orders containing their products
+	sellerRoutes := rh.SellerRoutes()
+	sellerRoutes.Get("/orders", handler.GetSellerOrders)
+	sellerRoutes.Patch("/orders/:id", handler.UpdateOrderStatus)
+	sellerRoutes.Get("/balance", handler.GetSellerBalance)
 
 }
 
@@ -63,7 +113,9 @@ func (h *UserHandler) Register(ctx *fiber.Ctx) error {
 		})
 	}
 
-	token, err := h.svc.SignUp(user)
+	user.CartToken = cartToken(ctx, user.CartToken)
+
+	token, notices, err := h.svc.SignUp(user)
 	if err != nil {
 		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{
 			"message": "Error on signup",
@@ -71,8 +123,9 @@ func (h *UserHandler) Register(ctx *fiber.Ctx) error {
 	}
 
 	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
-		"message": "register",
-		"token":   token,
+		"message":      "register",
+		"token":        token,
+		"cart_notices": notices,
 	})
 }
 
@@ -87,7 +140,9 @@ func (h *UserHandler) Login(ctx *fiber.Ctx) error {
 		})
 	}
 
-	token, err := h.svc.Login(loginInput.Email, loginInput.Password)
+	loginInput.CartToken = cartToken(ctx, loginInput.CartToken)
+
+	token, notices, err := h.svc.Login(loginInput)
 	if err != nil {
 		return ctx.Status(http.StatusUnauthorized).JSON(&fiber.Map{
 			"message": "please provide correct credentials",
@@ -95,11 +150,60 @@ func (h *UserHandler) Login(ctx *fiber.Ctx) error {
 	}
 
 	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
-		"message": "login",
-		"token":   token,
+		"message":      "login",
+		"token":        token,
+		"cart_notices": notices,
+	})
+}
+
+func (h *UserHandler) GetGuestCart(ctx *fiber.Ctx) error {
+
+	cart, err := h.svc.GetGuestCart(cartToken(ctx, ""))
+	if err != nil {
+		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "GetGuestCart",
+		"cart":    cart,
+	})
+}
+
+func (h *UserHandler) AddToGuestCart(ctx *fiber.Ctx) error {
+
+	req := dto.CreateCartRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": "please provide a valid product and qty",
+		})
+	}
+
+	token, cart, err := h.svc.AddToGuestCart(cartToken(ctx, ""), req)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message":    err.Error(),
+			"cart_token": token,
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message":    "cart updated successfully",
+		"cart":       cart,
+		"cart_token": token,
 	})
 }
 
+// cartToken is the guest cart token of the request, from the body or the
+// X-Cart-Token header
+func cartToken(ctx *fiber.Ctx, bodyToken string) string {
+	if len(bodyToken) > 0 {
+		return bodyToken
+	}
+	return ctx.Get("X-Cart-Token")
+}
+
 func (h *UserHandler) GetVerificationCode(ctx *fiber.Ctx) error {
 
 	user := h.svc.Auth.GetCurrentUser(ctx)
@@ -158,58 +262,464 @@ func (h *UserHandler) GetProfile(ctx *fiber.Ctx) error {
 	})
 }
 
+func (h *UserHandler) CreateAddress(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	req := dto.AddressRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": "please provide a valid address",
+		})
+	}
+
+	address, err := h.svc.CreateAddress(req, user)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "address created",
+		"address": address,
+	})
+}
+
+func (h *UserHandler) GetAddresses(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	addresses, err := h.svc.GetAddresses(user)
+	if err != nil {
+		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message":   "GetAddresses",
+		"addresses": addresses,
+	})
+}
+
+func (h *UserHandler) DeleteAddress(ctx *fiber.Ctx) error {
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	err := h.svc.DeleteAddress(uint(id), user)
+	if err != nil {
+		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{
+			"message": "failed to delete address",
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "address deleted",
+	})
+}
+
+func (h *UserHandler) GetShippingRates(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	// /users/cart/shipping-rates?address_id=1
+	rates, err := h.svc.GetShippingRates(uint(ctx.QueryInt("address_id")), user)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "GetShippingRates",
+		"rates":   rates,
+	})
+}
+
 func (h *UserHandler) AddToCart(ctx *fiber.Ctx) error {
+
+	req := dto.CreateCartRequest{}
+
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": "please provide a valid product and qty",
+		})
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	cartItems, err := h.svc.CreateCart(req, user)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "cart updated successfully",
+		"cart":    cartItems,
+	})
+}
+
+func (h *UserHandler) SaveForLater(ctx *fiber.Ctx) error {
+
+	req := dto.SaveForLaterRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": "please provide a valid product",
+		})
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	wishlist, err := h.svc.SaveForLater(req, user)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message":  "saved for later",
+		"wishlist": wishlist,
+	})
+}
+
+func (h *UserHandler) GetWishlist(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	wishlist, err := h.svc.GetWishlist(user)
+	if err != nil {
+		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message":  "GetWishlist",
+		"wishlist": wishlist,
+	})
+}
+
+func (h *UserHandler) AddToWishlist(ctx *fiber.Ctx) error {
+
+	req := dto.WishlistRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": "please provide a valid product",
+		})
+	}
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	wishlist, err := h.svc.AddToWishlist(req, user)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message":  "wishlist updated successfully",
+		"wishlist": wishlist,
+	})
+}
+
+func (h *UserHandler) RemoveFromWishlist(ctx *fiber.Ctx) error {
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	wishlist, err := h.svc.RemoveFromWishlist(uint(id), user)
+	if err != nil {
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message":  "wishlist updated successfully",
+		"wishlist": wishlist,
+	})
+}
+
+func (h *UserHandler) MoveToCart(ctx *fiber.Ctx) error {
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	// the body is optional, one unit is moved without it
+	req := dto.MoveToCartRequest{}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+				"message": "please provide a valid qty",
+			})
+		}
+	}
+
+	cartItems, err := h.svc.MoveToCart(uint(id), req, user)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
 	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
-		"message": "AddToCart",
+		"message": "moved to cart",
+		"cart":    cartItems,
 	})
 }
 
 func (h *UserHandler) GetCart(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	cart, err := h.svc.FindCart(user.ID)
+	if err != nil {
+		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{
+			"message": "cart does not exist",
+		})
+	}
+
+	// taxes are estimated for /cart?country=DE&region=
+	summary, err := h.svc.GetCartSummary(shippingLocation(ctx), user)
+	if err != nil {
+		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
 	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
 		"message": "GetCart",
+		"cart":    cart,
+		"summary": summary,
+	})
+}
+
+func (h *UserHandler) ApplyCoupon(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	req := dto.ApplyCouponRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": "please provide a valid coupon code",
+		})
+	}
+
+	summary, err := h.svc.ApplyCoupon(req, shippingLocation(ctx), user)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "coupon applied",
+		"summary": summary,
+	})
+}
+
+func (h *UserHandler) RemoveCoupon(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	summary, err := h.svc.RemoveCoupon(shippingLocation(ctx), user)
+	if err != nil {
+		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "coupon removed",
+		"summary": summary,
 	})
 }
 
 func (h *UserHandler) GetOrders(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	orders, err := h.svc.GetOrders(user)
+	if err != nil {
+		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
 	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
 		"message": "GetOrders",
+		"orders":  orders,
 	})
 }
 
 func (h *UserHandler) GetOrder(ctx *fiber.Ctx) error {
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	order, err := h.svc.GetOrderById(uint(id), user.ID)
+	if err != nil {
+		return ctx.Status(http.StatusNotFound).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
 	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
 		"message": "GetOrder",
+		"order":   order,
 	})
 }
 
 func (h *UserHandler) CreateOrder(ctx *fiber.Ctx) error {
+
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	// the body is optional for buyers with a single address whose sellers
+	// offer one shipping method each
+	req := dto.CreateOrderRequest{}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+				"message": "please provide a delivery address and shipping methods",
+			})
+		}
+	}
+
+	orderId, err := h.svc.CreateOrder(req, user)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
 	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
-		"message": "CreateOrder",
+		"message":  "order created successfully, please complete the payment",
+		"order_id": orderId,
 	})
 }
 
-func (h *UserHandler) BecomeSeller(ctx *fiber.Ctx) error {
+func (h *UserHandler) PayOrder(ctx *fiber.Ctx) error {
 
+	id, _ := strconv.Atoi(ctx.Params("id"))
 	user := h.svc.Auth.GetCurrentUser(ctx)
 
-	req := dto.SellerInput{}
+	req := dto.PaymentRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": "please provide valid payment details",
+		})
+	}
+
+	order, err := h.svc.PayOrder(uint(id), req, user)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "payment successful",
+		"order":   order,
+	})
+}
+
+func (h *UserHandler) CancelOrder(ctx *fiber.Ctx) error {
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	user := h.svc.Auth.GetCurrentUser(ctx)
+
+	order, err := h.svc.CancelOrder(uint(id), user)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "order cancelled",
+		"order":   order,
+	})
+}
+
+func (h *UserHandler) GetSellerOrders(ctx *fiber.Ctx) error {
+
+	seller := h.svc.Auth.GetCurrentUser(ctx)
 
-	err := ctx.BodyParser(&req)
+	orders, err := h.svc.GetSellerOrders(seller)
 	if err != nil {
+		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "GetSellerOrders",
+		"orders":  orders,
+	})
+}
+
+func (h *UserHandler) UpdateOrderStatus(ctx *fiber.Ctx) error {
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	seller := h.svc.Auth.GetCurrentUser(ctx)
+
+	req := dto.UpdateOrderStatusRequest{}
+	if err := ctx.BodyParser(&req); err != nil {
 		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
-			"message": "request parameters are not valid",
+			"message": "please provide a valid status",
 		})
 	}
 
-	token, err := h.svc.BecomeSeller(user.ID, req)
+	order, err := h.svc.UpdateOrderStatus(uint(id), req, seller)
+	if err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
+		"message": "order status updated",
+		"order":   order,
+	})
+}
+
+func (h *UserHandler) GetSellerBalance(ctx *fiber.Ctx) error {
+
+	var page dto.PaginationRequest
+	if err := ctx.QueryParser(&page); err != nil {
+		return ctx.Status(http.StatusBadRequest).JSON(&fiber.Map{
+			"message": "pagination parameters are not valid",
+		})
+	}
+
+	seller := h.svc.Auth.GetCurrentUser(ctx)
+
+	balance, entries, pagination, err := h.svc.GetSellerBalance(page, seller)
 	if err != nil {
 		return ctx.Status(http.StatusInternalServerError).JSON(&fiber.Map{
-			"message": "failed to become seller",
+			"message": err.Error(),
 		})
 	}
 
 	return ctx.Status(http.StatusOK).JSON(&fiber.Map{
-		"message": "become seller",
-		"token":   token,
+		"message":    "GetSellerBalance",
+		"balance":    balance,
+		"entries":    entries,
+		"pagination": pagination,
 	})
 }
+
+// shippingLocation reads the optional country and region query parameters
+func shippingLocation(ctx *fiber.Ctx) dto.ShippingLocation {
+	var location dto.ShippingLocation
+	if err := ctx.QueryParser(&location); err != nil {
+		return dto.ShippingLocation{}
+	}
+	return location
+}
@@ -3,6 +3,7 @@ package rest
 import (
 	"go-ecommerce-app/config"
 	"go-ecommerce-app/internal/helper"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -13,4 +14,44 @@ type RestHandler struct {
 	DB     *gorm.DB
 	Auth   helper.Auth
 	Config config.AppConfig
+
+	groups map[string]fiber.Router
+}
+
+// UserRoutes, SellerRoutes and AdminRoutes are the route groups shared by all
+// handlers, so the token of a request is verified once. Public routes under
+// the same prefix have to be registered before the group is first used.
+// Fiber matches group middleware by plain string prefix, so a group only
+// authorizes its own path and the ones below it: /seller guards /seller/profile
+// but leaves the public /sellers/:id storefront alone.
+func (rh *RestHandler) UserRoutes() fiber.Router {
+	return rh.group("/users", rh.Auth.Authorize)
+}
+
+func (rh *RestHandler) SellerRoutes() fiber.Router {
+	return rh.group("/seller", rh.Auth.AuthorizeSeller)
+}
+
+func (rh *RestHandler) AdminRoutes() fiber.Router {
+	return rh.group("/admin", rh.Auth.AuthorizeAdmin)
+}
+
+func (rh *RestHandler) group(prefix string, authorize fiber.Handler) fiber.Router {
+	if rh.groups == nil {
+		rh.groups = make(map[string]fiber.Router)
+	}
+
+	group, ok := rh.groups[prefix]
+	if !ok {
+		group = rh.App.Group(prefix, func(ctx *fiber.Ctx) error {
+			path := strings.ToLower(ctx.Path())
+			if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+				return ctx.Next()
+			}
+			return authorize(ctx)
+		})
+		rh.groups[prefix] = group
+	}
+
+	return group
 }
@@ -6,7 +6,10 @@ import (
 	"go-ecommerce-app/internal/api/rest/handlers"
 	"go-ecommerce-app/internal/domain"
 	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/pkg/payment"
 	"log"
+	"path/filepath"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -15,7 +18,16 @@ import (
 )
 
 func StartServer(config config.AppConfig) {
-	app := fiber.New()
+	_, err := payment.NewPaymentClient(config)
+	if err != nil {
+		log.Fatalf("Payment gateway error: %v, set PAYMENT_SANDBOX=true to use the sandbox", err.Error())
+	}
+	log.Println("payments go through the sandbox gateway, every payment is approved")
+
+	app := fiber.New(fiber.Config{
+		// room for a batch of product images in one upload
+		BodyLimit: 32 << 20,
+	})
 
 	db, err := gorm.Open(postgres.Open(config.Dsn), &gorm.Config{})
 	if err != nil {
@@ -24,21 +36,79 @@ func StartServer(config config.AppConfig) {
 
 	log.Println("Database connected")
 	// run migrations
-	err = db.AutoMigrate(&domain.User{}, &domain.BankAccount{}, &domain.Category{}, &domain.Product{})
+	err = db.AutoMigrate(
+		&domain.User{},
+		&domain.BankAccount{},
+		&domain.Category{},
+		&domain.Product{},
+		&domain.ProductOption{},
+		&domain.ProductVariant{},
+		&domain.ProductImage{},
+		&domain.Cart{},
+		&domain.Order{},
+		&domain.OrderItem{},
+		&domain.Review{},
+		&domain.StockReservation{},
+		&domain.InventoryMovement{},
+		&domain.Notification{},
+		&domain.Coupon{},
+		&domain.CouponRedemption{},
+		&domain.CartCoupon{},
+		&domain.PriceHistory{},
+		&domain.ExchangeRate{},
+		&domain.TaxRule{},
+		&domain.OrderItemTax{},
+		&domain.Address{},
+		&domain.ShippingProfile{},
+		&domain.Shipment{},
+		&domain.ReturnRequest{},
+		&domain.BalanceEntry{},
+		&domain.Invoice{},
+		&domain.InvoiceSequence{},
+		&domain.WishlistItem{},
+		&domain.CartReminder{},
+		&domain.CartReminderItem{},
+		&domain.SellerProfile{},
+		&domain.SellerApplication{},
+		&domain.SellerDocument{},
+	)
 	if err != nil {
 		log.Fatalf("Error on running migration: %v", err.Error())
 	}
+
+	err = migrateMoneyColumns(db, config.Currency)
+	if err != nil {
+		log.Fatalf("Error on migrating prices: %v", err.Error())
+	}
+
+	err = backfillShipments(db)
+	if err != nil {
+		log.Fatalf("Error on migrating shipments: %v", err.Error())
+	}
+
+	err = backfillSellerBalance(db)
+	if err != nil {
+		log.Fatalf("Error on migrating seller balances: %v", err.Error())
+	}
 	log.Println("migration was succefull")
 
 	// CORS Middleware setup
 	c := cors.New(cors.Config{
 		AllowOrigins: "http://localhost:3000",
-		AllowHeaders: "Content-Type, Accept, Authorization",
+		AllowHeaders: "Content-Type, Accept, Accept-Currency, Authorization, X-Cart-Token",
 		AllowMethods: "GET, POST, PUT, PATCH, DELETE, OPTIONS",
 	})
 
 	app.Use(c)
 
+	// private files must not be served with the uploaded images
+	if rel, err := filepath.Rel(config.StorageDir, config.PrivateStorageDir); err == nil && !strings.HasPrefix(rel, "..") {
+		log.Fatalf("PRIVATE_STORAGE_DIR must not be inside STORAGE_DIR")
+	}
+
+	// uploaded images kept by the local storage
+	app.Static(config.StorageBaseUrl, config.StorageDir)
+
 	auth := helper.SetupAuth(config.AppSecret)
 
 	rh := &rest.RestHandler{
@@ -48,6 +118,7 @@ func StartServer(config config.AppConfig) {
 		Config: config,
 	}
 	setupRoutes(rh)
+	startJobs(rh)
 
 	app.Listen(config.ServerPort)
 }
@@ -58,5 +129,23 @@ func setupRoutes(rh *rest.RestHandler) {
 	// transactions
 	// catalouges
 	handlers.SetupCatalogRoutes(rh)
+	// reviews
+	handlers.SetupReviewRoutes(rh)
+	// coupons
+	handlers.SetupCouponRoutes(rh)
+	// currencies
+	handlers.SetupCurrencyRoutes(rh)
+	// taxes
+	handlers.SetupTaxRoutes(rh)
+	// shipping
+	handlers.SetupShippingRoutes(rh)
+	// returns and refunds
+	handlers.SetupReturnRoutes(rh)
+	// invoices
+	handlers.SetupInvoiceRoutes(rh)
+	// abandoned cart reminders
+	handlers.SetupCartReminderRoutes(rh)
+	// seller storefronts
+	handlers.SetupSellerRoutes(rh)
 
 }
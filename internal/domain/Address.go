@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// PostalAddress is where a parcel is delivered
+type PostalAddress struct {
+	Name     string `json:"name"`
+	Line1    string `json:"line1"`
+	Line2    string `json:"line2"`
+	City     string `json:"city"`
+	PostCode string `json:"post_code"`
+	Region   string `json:"region"`
+	Country  string `json:"country" gorm:"size:2"` // ISO 3166-1 alpha-2
+	Phone    string `json:"phone"`
+}
+
+// Address is an entry of the buyer's address book
+type Address struct {
+	ID            uint `json:"id" gorm:"primaryKey"`
+	UserId        uint `json:"user_id" gorm:"index"`
+	PostalAddress `gorm:"embedded"`
+	CreatedAt     time.Time `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"default:current_timestamp"`
+}
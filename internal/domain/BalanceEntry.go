@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+const (
+	BalanceSale   = "sale"
+	BalanceRefund = "refund"
+)
+
+// BalanceEntry is a change to what the store owes a seller: paid shipments
+// add to the balance, refunds take from it
+type BalanceEntry struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	SellerId        uint      `json:"seller_id" gorm:"index"`
+	Type            string    `json:"type"`
+	OrderId         uint      `json:"order_id"`
+	ShipmentId      uint      `json:"shipment_id"`
+	ReturnRequestId uint      `json:"return_request_id"`
+	Amount          Money     `json:"amount" gorm:"embedded;embeddedPrefix:amount_"` // negative for refunds
+	CreatedAt       time.Time `json:"created_at" gorm:"default:current_timestamp"`
+}
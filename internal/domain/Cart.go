@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+type Cart struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserId    uint      `json:"user_id" gorm:"index"` // 0 for guest carts
+	GuestId   string    `json:"-" gorm:"index"`       // id in the cart token of a guest
+	ProductId uint      `json:"product_id"`
+	VariantId uint      `json:"variant_id"`
+	Sku       string    `json:"sku"`
+	Name      string    `json:"name"`
+	ImageUrl  string    `json:"image_url"`
+	SellerId  uint      `json:"seller_id"`
+	Price     Money     `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Qty       uint      `json:"qty"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:current_timestamp"`
+}
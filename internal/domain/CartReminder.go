@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// CartRecoveryWindow is how long after a reminder a paid order counts as
+// recovered by it
+const CartRecoveryWindow = 7 * 24 * time.Hour
+
+// CartReminder is a reminder sent to a buyer who left items in their cart.
+// A cart gets one reminder until it is changed again.
+type CartReminder struct {
+	ID            uint               `json:"id" gorm:"primaryKey"`
+	UserId        uint               `json:"user_id" gorm:"index"`
+	CartUpdatedAt time.Time          `json:"cart_updated_at"` // last change to the cart when reminded
+	Items         []CartReminderItem `json:"items"`
+	SentAt        time.Time          `json:"sent_at" gorm:"index"`
+	RecoveredAt   *time.Time         `json:"recovered_at"`
+	OrderId       uint               `json:"order_id"` // the order that recovered the cart
+	CreatedAt     time.Time          `json:"created_at" gorm:"default:current_timestamp"`
+}
+
+// CartReminderItem is a cart line at the time of the reminder, it lets
+// sellers see the reminders about their products
+type CartReminderItem struct {
+	ID             uint  `json:"id" gorm:"primaryKey"`
+	CartReminderId uint  `json:"cart_reminder_id" gorm:"index"`
+	ProductId      uint  `json:"product_id"`
+	SellerId       uint  `json:"seller_id" gorm:"index"`
+	Qty            uint  `json:"qty"`
+	Price          Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+}
+
+// AbandonedCart is the cart of a user due a reminder
+type AbandonedCart struct {
+	UserId      uint
+	LastUpdated time.Time
+}
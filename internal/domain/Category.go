@@ -1,14 +1,22 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Category struct {
-	ID           uint      `json:"id" gorm:"primaryKey"`
-	Name         string    `json:"name" gorm:"index;"`
-	ParentId     uint      `json:"parent_id"`
-	ImageUrl     string    `json:"image_url"`
-	Products     []Product `json:"products"`
-	DisplayOrder int       `json:"display_order"`
-	CreatedAt    time.Time `json:"created_at" gorm:"default:current_timestamp"`
-	UpdatedAt    time.Time `json:"updated_at" gorm:"default:current_timestamp"`
+	ID                uint           `json:"id" gorm:"primaryKey"`
+	Name              string         `json:"name" gorm:"index;"`
+	ParentId          uint           `json:"parent_id"`
+	ImageUrl          string         `json:"image_url"`
+	ImageKey          string         `json:"-"` // set when the image was uploaded to storage
+	Products          []Product      `json:"products,omitempty"`
+	DisplayOrder      int            `json:"display_order"`
+	ProductCount      int64          `json:"product_count" gorm:"-"`
+	TotalProductCount int64          `json:"total_product_count" gorm:"-"`
+	CreatedAt         time.Time      `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt         time.Time      `json:"updated_at" gorm:"default:current_timestamp"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
 }
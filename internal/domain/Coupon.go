@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+const (
+	CouponPercentage = "percentage"
+	CouponFixed      = "fixed"
+)
+
+type Coupon struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Code         string     `json:"code" gorm:"index;unique;not null"`
+	Type         string     `json:"type"`
+	Percent      float64    `json:"percent"`                                       // percent off for percentage coupons
+	Amount       Money      `json:"amount" gorm:"embedded;embeddedPrefix:amount_"` // amount off for fixed coupons
+	MinOrder     Money      `json:"min_order" gorm:"embedded;embeddedPrefix:min_order_"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	UsageLimit   int        `json:"usage_limit"`                         // total redemptions, 0 is unlimited
+	PerUserLimit int        `json:"per_user_limit"`                      // redemptions per user, 0 is unlimited
+	CategoryIds  []uint     `json:"category_ids" gorm:"serializer:json"` // only items in these categories
+	SellerIds    []uint     `json:"seller_ids" gorm:"serializer:json"`   // only items of these sellers
+	Active       bool       `json:"active" gorm:"default:true"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+// CouponRedemption is a use of a coupon by an order
+type CouponRedemption struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CouponId  uint      `json:"coupon_id" gorm:"index"`
+	UserId    uint      `json:"user_id" gorm:"index"`
+	OrderId   uint      `json:"order_id" gorm:"index"`
+	Amount    Money     `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:current_timestamp"`
+}
+
+// CartCoupon is the coupon a user applied to their cart
+type CartCoupon struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserId    uint      `json:"user_id" gorm:"uniqueIndex"`
+	CouponId  uint      `json:"coupon_id"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+func (c Coupon) IsUsable(now time.Time) error {
+	if !c.Active {
+		return errors.New("coupon is not active")
+	}
+	if c.ExpiresAt != nil && !now.Before(*c.ExpiresAt) {
+		return errors.New("coupon has expired")
+	}
+	return nil
+}
+
+// AppliesTo tells if an item of the given category and seller is discounted
+func (c Coupon) AppliesTo(categoryId uint, sellerId uint) bool {
+	if len(c.CategoryIds) > 0 && !slices.Contains(c.CategoryIds, categoryId) {
+		return false
+	}
+	if len(c.SellerIds) > 0 && !slices.Contains(c.SellerIds, sellerId) {
+		return false
+	}
+	return true
+}
+
+// Discount returns the amount taken off the given eligible subtotal
+func (c Coupon) Discount(eligible Money) (Money, error) {
+	if !eligible.IsPositive() {
+		return Money{}, errors.New("coupon does not apply to any item in the cart")
+	}
+
+	cmp, err := eligible.Cmp(c.MinOrder)
+	if err != nil {
+		return Money{}, errors.New("coupon can not be used for this currency")
+	}
+	if cmp < 0 {
+		return Money{}, errors.New("order total is below the minimum for this coupon")
+	}
+
+	var discount Money
+	switch c.Type {
+	case CouponPercentage:
+		discount = eligible.Percent(c.Percent)
+	case CouponFixed:
+		if !eligible.SameCurrency(c.Amount) {
+			return Money{}, errors.New("coupon can not be used for this currency")
+		}
+		discount = c.Amount
+	}
+
+	return MinMoney(discount, eligible), nil
+}
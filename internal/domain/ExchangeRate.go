@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ExchangeRate is how many units of Currency one unit of the base currency
+// (the configured store currency) buys
+type ExchangeRate struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Currency  string    `json:"currency" gorm:"size:3;uniqueIndex;not null"`
+	Rate      float64   `json:"rate"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+// ExchangeRates converts amounts between the base currency and the
+// currencies with a rate
+type ExchangeRates struct {
+	Base  string
+	Rates map[string]float64
+}
+
+func NewExchangeRates(base string, rates []*ExchangeRate) ExchangeRates {
+	r := ExchangeRates{Base: base, Rates: map[string]float64{base: 1}}
+	for _, rate := range rates {
+		if rate.Rate > 0 {
+			r.Rates[rate.Currency] = rate.Rate
+		}
+	}
+	return r
+}
+
+func (r ExchangeRates) Supports(currency string) bool {
+	_, ok := r.Rates[currency]
+	return ok
+}
+
+// Convert returns the amount in the given currency rounded to its minor unit
+func (r ExchangeRates) Convert(m Money, to string) (Money, error) {
+	if m.Currency == to || (m.Amount == 0 && len(m.Currency) == 0) {
+		return Money{Amount: m.Amount, Currency: to}, nil
+	}
+
+	from, ok := r.Rates[m.Currency]
+	if !ok {
+		return Money{}, fmt.Errorf("currency %v is not supported", m.Currency)
+	}
+
+	rate, ok := r.Rates[to]
+	if !ok {
+		return Money{}, fmt.Errorf("currency %v is not supported", to)
+	}
+
+	major := float64(m.Amount) / math.Pow10(MinorUnits(m.Currency))
+	amount := math.Round(major / from * rate * math.Pow10(MinorUnits(to)))
+
+	return Money{Amount: int64(amount), Currency: to}, nil
+}
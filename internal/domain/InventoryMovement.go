@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+const (
+	MovementRestock    = "restock"
+	MovementDamage     = "damage"
+	MovementCorrection = "correction"
+	MovementSale       = "sale"
+	MovementReturn     = "return"
+	MovementCancel     = "cancellation" // a paid order was cancelled
+)
+
+// InventoryMovement records every change to the stock of a product or variant
+type InventoryMovement struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ProductId  uint      `json:"product_id" gorm:"index"`
+	VariantId  uint      `json:"variant_id"`
+	UserId     uint      `json:"user_id"` // who made the change, 0 for system changes
+	OrderId    uint      `json:"order_id"`
+	Change     int       `json:"change"`
+	Reason     string    `json:"reason"`
+	Note       string    `json:"note"`
+	StockAfter uint      `json:"stock_after"`
+	CreatedAt  time.Time `json:"created_at" gorm:"default:current_timestamp"`
+}
+
+// ValidChange tells if the direction of the change fits the reason
+func (m InventoryMovement) ValidChange() bool {
+	switch m.Reason {
+	case MovementRestock, MovementReturn, MovementCancel:
+		return m.Change > 0
+	case MovementDamage, MovementSale:
+		return m.Change < 0
+	case MovementCorrection:
+		return m.Change != 0
+	}
+	return false
+}
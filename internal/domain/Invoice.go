@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Invoice is issued by a seller for their shipment of a paid order. Every
+// seller numbers their invoices 1, 2, 3... without gaps. The invoice of a
+// cancelled order is cancelled by a credit note, which takes the next
+// number of the same sequence.
+type Invoice struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	SellerId   uint       `json:"seller_id" gorm:"uniqueIndex:idx_invoice_seller_number"`
+	Number     uint       `json:"number" gorm:"uniqueIndex:idx_invoice_seller_number"`
+	Code       string     `json:"code"` // printed number, e.g. INV-12-000042
+	OrderId    uint       `json:"order_id" gorm:"index"`
+	ShipmentId uint       `json:"shipment_id" gorm:"uniqueIndex"`
+	UserId     uint       `json:"user_id" gorm:"index"`
+	Amount     Money      `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	IssuedAt   time.Time  `json:"issued_at"`
+	CreditNote string     `json:"credit_note"` // code of the credit note, e.g. CN-12-000043
+	CreditedAt *time.Time `json:"credited_at"`
+	StorageKey string     `json:"-"` // empty until the pdf is stored
+	CreatedAt  time.Time  `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+// InvoiceSequence holds the last invoice number of a seller
+type InvoiceSequence struct {
+	SellerId uint `gorm:"primaryKey;autoIncrement:false"`
+	Last     uint
+}
+
+func InvoiceCode(sellerId uint, number uint) string {
+	return fmt.Sprintf("INV-%v-%06d", sellerId, number)
+}
+
+func CreditNoteCode(sellerId uint, number uint) string {
+	return fmt.Sprintf("CN-%v-%06d", sellerId, number)
+}
+
+func (i Invoice) FileName() string {
+	return fmt.Sprintf("%v.pdf", i.Code)
+}
@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in the minor unit of its currency (cents for USD) with
+// the ISO 4217 currency code. It is stored in two columns, use it with
+// gorm:"embedded;embeddedPrefix:<column>_".
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency" gorm:"size:3"`
+}
+
+// currencies without the usual two decimal places
+var minorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "JPY": 0, "KRW": 0, "PYG": 0, "UGX": 0, "VND": 0, "XAF": 0, "XOF": 0,
+	"BHD": 3, "JOD": 3, "KWD": 3, "OMR": 3, "TND": 3,
+}
+
+// MinorUnits returns the number of decimal places of the currency
+func MinorUnits(currency string) int {
+	if n, ok := minorUnits[currency]; ok {
+		return n
+	}
+	return 2
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney reads a decimal amount like "19.99" without going through a float
+func ParseMoney(s string, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	digits := MinorUnits(currency)
+	if len(whole) == 0 || len(fraction) > digits || strings.Trim(whole+fraction, "0123456789") != "" {
+		return Money{}, fmt.Errorf("%v is not a valid %v amount", s, currency)
+	}
+
+	amount, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", digits-len(fraction)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%v is not a valid %v amount", s, currency)
+	}
+
+	if negative {
+		amount = -amount
+	}
+
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// Decimal formats the amount in major units, e.g. "19.99"
+func (m Money) Decimal() string {
+	digits := MinorUnits(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	if digits == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+
+	scale := int64(math.Pow10(digits))
+	return fmt.Sprintf("%v%d.%0*d", sign, amount/scale, digits, amount%scale)
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// SameCurrency tells if both amounts can be added or compared, a zero amount
+// without currency goes with any currency
+func (m Money) SameCurrency(o Money) bool {
+	return m.Currency == o.Currency ||
+		(m.Amount == 0 && len(m.Currency) == 0) ||
+		(o.Amount == 0 && len(o.Currency) == 0)
+}
+
+func (m Money) currencyWith(o Money) string {
+	if len(m.Currency) > 0 {
+		return m.Currency
+	}
+	return o.Currency
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	if !m.SameCurrency(o) {
+		return Money{}, errCurrencyMismatch(m, o)
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.currencyWith(o)}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if !m.SameCurrency(o) {
+		return Money{}, errCurrencyMismatch(m, o)
+	}
+	return Money{Amount: m.Amount - o.Amount, Currency: m.currencyWith(o)}, nil
+}
+
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+// Percent returns p percent of the amount rounded half away from zero
+func (m Money) Percent(p float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * p / 100)), Currency: m.Currency}
+}
+
+// Share returns part/whole of the amount, rounded down, used to spread a
+// discount over order lines
+func (m Money) Share(part int64, whole int64) Money {
+	if whole == 0 {
+		return Money{Currency: m.Currency}
+	}
+	return Money{Amount: m.Amount * part / whole, Currency: m.Currency}
+}
+
+// Cmp returns -1, 0 or 1 when m is lower, equal or greater than o
+func (m Money) Cmp(o Money) (int, error) {
+	if !m.SameCurrency(o) {
+		return 0, errCurrencyMismatch(m, o)
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func MinMoney(a Money, b Money) Money {
+	if a.SameCurrency(b) && b.Amount < a.Amount {
+		return b
+	}
+	return a
+}
+
+func errCurrencyMismatch(a Money, b Money) error {
+	return errors.New("can not combine " + a.Currency + " and " + b.Currency + " amounts")
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+// Notification is a message queued for a user, a background job sends it
+// by sms to the user's phone
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserId    uint       `json:"user_id" gorm:"index"`
+	Message   string     `json:"message"`
+	Status    string     `json:"status" gorm:"index;default:pending"`
+	Attempts  int        `json:"attempts"`
+	LastError string     `json:"last_error"`
+	SentAt    *time.Time `json:"sent_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"default:current_timestamp"`
+}
@@ -0,0 +1,119 @@
+package domain
+
+import "time"
+
+const (
+	OrderPending   = "pending" // waiting for payment, stock is reserved
+	OrderPaid      = "paid"
+	OrderShipped   = "shipped"
+	OrderDelivered = "delivered"
+	OrderExpired   = "expired"
+	OrderCancelled = "cancelled"
+)
+
+// RefundPending is the refund id of a cancelled order while its refund is
+// sent to the gateway
+const RefundPending = "pending"
+
+// nextOrderStatus lists the status an order moves to from its current status
+var nextOrderStatus = map[string]string{
+	OrderPaid:    OrderShipped,
+	OrderShipped: OrderDelivered,
+}
+
+// fulfilmentStage orders the statuses a shipment goes through once paid
+var fulfilmentStage = map[string]int{
+	OrderPaid:      1,
+	OrderShipped:   2,
+	OrderDelivered: 3,
+}
+
+// StatusOf is the status of an order with these shipments, the status of
+// the shipment furthest behind
+func StatusOf(shipments []Shipment) string {
+	status := ""
+	for _, s := range shipments {
+		stage, ok := fulfilmentStage[s.Status]
+		if !ok {
+			// not paid yet or no longer fulfilled
+			return s.Status
+		}
+		if len(status) == 0 || stage < fulfilmentStage[status] {
+			status = s.Status
+		}
+	}
+	return status
+}
+
+type Order struct {
+	ID            uint          `json:"id" gorm:"primaryKey"`
+	UserId        uint          `json:"user_id" gorm:"index"`
+	Status        string        `json:"status" gorm:"default:pending"` // follows the shipments once paid
+	Subtotal      Money         `json:"subtotal" gorm:"embedded;embeddedPrefix:subtotal_"`
+	Discount      Money         `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	CouponCode    string        `json:"coupon_code"`
+	Tax           Money         `json:"tax" gorm:"embedded;embeddedPrefix:tax_"`       // all taxes, inclusive ones are already part of the subtotal
+	Amount        Money         `json:"amount" gorm:"embedded;embeddedPrefix:amount_"` // subtotal minus discount plus exclusive taxes and shipping, what the buyer pays
+	Country       string        `json:"country"`                                       // where the order is shipped, decides the taxes
+	Region        string        `json:"region"`
+	AddressId     uint          `json:"address_id"`
+	ShipTo        PostalAddress `json:"ship_to" gorm:"embedded;embeddedPrefix:ship_to_"` // copy of the address at checkout
+	ShippingTotal Money         `json:"shipping_total" gorm:"embedded;embeddedPrefix:shipping_total_"`
+	Shipments     []Shipment    `json:"shipments"` // one per seller
+	TransactionId string        `json:"transaction_id"`
+	ExpiresAt     time.Time     `json:"expires_at"` // pending orders are released after this
+	PaidAt        *time.Time    `json:"paid_at"`
+	CancelledAt   *time.Time    `json:"cancelled_at"`
+	RefundId      string        `json:"refund_id"` // refund of a paid order that was cancelled
+	Items         []OrderItem   `json:"items"`
+	CreatedAt     time.Time     `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt     time.Time     `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+type OrderItem struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	OrderId    uint           `json:"order_id" gorm:"index"`
+	ProductId  uint           `json:"product_id"`
+	VariantId  uint           `json:"variant_id"`
+	Sku        string         `json:"sku"`
+	Name       string         `json:"name"`
+	ImageUrl   string         `json:"image_url"`
+	SellerId   uint           `json:"seller_id"`
+	CategoryId uint           `json:"category_id"`
+	Price      Money          `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Qty        uint           `json:"qty"`
+	Discount   Money          `json:"discount" gorm:"embedded;embeddedPrefix:discount_"` // share of the order discount for this line
+	TaxClass   string         `json:"tax_class"`
+	Weight     uint           `json:"weight"` // shipping weight of one unit in grams
+	Tax        Money          `json:"tax" gorm:"embedded;embeddedPrefix:tax_"`
+	Taxes      []OrderItemTax `json:"taxes"`
+	Product    *Product       `json:"product,omitempty"` // loaded including deleted products
+	CreatedAt  time.Time      `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt  time.Time      `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+// Cancellable tells if the buyer can still cancel the order, which is until
+// one of its shipments has been sent
+func (o Order) Cancellable() bool {
+	if o.Status != OrderPending && o.Status != OrderPaid {
+		return false
+	}
+	for _, s := range o.Shipments {
+		if s.Status != OrderPending && s.Status != OrderPaid {
+			return false
+		}
+	}
+	return true
+}
+
+// Paid is what the buyer paid for the line: the price of all units less the
+// discount plus exclusive taxes
+func (i OrderItem) Paid() Money {
+	paid := NewMoney(i.Price.Amount*int64(i.Qty)-i.Discount.Amount, i.Price.Currency)
+	for _, tax := range i.Taxes {
+		if !tax.Inclusive {
+			paid.Amount += tax.Amount.Amount
+		}
+	}
+	return paid
+}
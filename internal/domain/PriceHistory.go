@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// PriceHistory is the price a product had, or will have, from EffectiveFrom
+// until the next entry. Entries of a variant have its VariantId, those of the
+// product 0.
+type PriceHistory struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ProductId     uint      `json:"product_id" gorm:"index:idx_price_history_product_time"`
+	VariantId     uint      `json:"variant_id" gorm:"not null;default:0"`
+	Price         Money     `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	EffectiveFrom time.Time `json:"effective_from" gorm:"index:idx_price_history_product_time"`
+	CreatedAt     time.Time `json:"created_at" gorm:"default:current_timestamp"`
+}
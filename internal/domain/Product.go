@@ -1,16 +1,128 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ProductDraft     = "draft"
+	ProductPublished = "published"
+	ProductUnlisted  = "unlisted" // reachable by link but left out of listings
+)
 
 type Product struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	Name        string    `json:"name" gorm:"index;"`
-	Description string    `json:"description"`
-	CategoryId  uint      `json:"category_id"`
-	ImageUrl    string    `json:"image_url"`
-	Price       float64   `json:"price"`
-	UserId      int       `json:"user_id"`
-	Stock       uint      `json:"stock"`
-	CreatedAt   time.Time `json:"created_at" gorm:"default:current_timestamp"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"default:current_timestamp"`
+	ID                    uint             `json:"id" gorm:"primaryKey"`
+	Name                  string           `json:"name" gorm:"index;"`
+	Sku                   string           `json:"sku" gorm:"uniqueIndex:idx_product_seller_sku,where:sku <> ''"`
+	Description           string           `json:"description"`
+	CategoryId            uint             `json:"category_id"`
+	ImageUrl              string           `json:"image_url"`                                   // first image of the gallery
+	Price                 Money            `json:"price" gorm:"embedded;embeddedPrefix:price_"` // regular price, shown as compare-at price during a sale
+	SalePrice             Money            `json:"sale_price" gorm:"embedded;embeddedPrefix:sale_price_"`
+	SaleStartsAt          *time.Time       `json:"sale_starts_at"`
+	SaleEndsAt            *time.Time       `json:"sale_ends_at"`
+	CurrentPrice          Money            `json:"current_price" gorm:"-"`
+	LowestPrice30d        Money            `json:"lowest_price_30d" gorm:"-"`        // lowest price of the last 30 days
+	DisplayPrice          *Money           `json:"display_price,omitempty" gorm:"-"` // current price in the currency the buyer asked for
+	DisplayLowestPrice30d *Money           `json:"display_lowest_price_30d,omitempty" gorm:"-"`
+	UserId                int              `json:"user_id" gorm:"uniqueIndex:idx_product_seller_sku"`
+	Stock                 uint             `json:"stock"`              // sum of variant stock when the product has variants
+	Available             uint             `json:"available" gorm:"-"` // stock minus active reservations
+	ReorderThreshold      uint             `json:"reorder_threshold"`  // seller is alerted when stock drops below, 0 disables
+	Options               []ProductOption  `json:"options,omitempty"`
+	Variants              []ProductVariant `json:"variants,omitempty"`
+	Images                []ProductImage   `json:"images,omitempty"`
+	TaxClass              string           `json:"tax_class" gorm:"default:standard"`
+	Weight                uint             `json:"weight"` // grams
+	Length                uint             `json:"length"` // millimetres
+	Width                 uint             `json:"width"`
+	Height                uint             `json:"height"`
+	Rating                float64          `json:"rating"` // average of visible reviews
+	ReviewCount           int              `json:"review_count"`
+	Status                string           `json:"status" gorm:"index;default:published"`
+	PublishAt             *time.Time       `json:"publish_at"`  // a draft is published at this time
+	ArchivedAt            *time.Time       `json:"archived_at"` // archived products are only visible to their seller
+	CreatedAt             time.Time        `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt             time.Time        `json:"updated_at" gorm:"default:current_timestamp"`
+	DeletedAt             gorm.DeletedAt   `json:"-" gorm:"index"`
+}
+
+// IsListed tells if the product shows up in public listings
+func (p Product) IsListed() bool {
+	return p.IsVisible() && p.Status == ProductPublished
+}
+
+// IsVisible tells if buyers can open and buy the product
+func (p Product) IsVisible() bool {
+	return p.ArchivedAt == nil && !p.DeletedAt.Valid &&
+		(p.Status == ProductPublished || p.Status == ProductUnlisted)
+}
+
+// OnSale tells if the sale price applies at the given time
+func (p Product) OnSale(now time.Time) bool {
+	if !p.SalePrice.IsPositive() || p.SalePrice.Currency != p.Price.Currency || p.SalePrice.Amount >= p.Price.Amount {
+		return false
+	}
+	if p.SaleStartsAt != nil && now.Before(*p.SaleStartsAt) {
+		return false
+	}
+	if p.SaleEndsAt != nil && !now.Before(*p.SaleEndsAt) {
+		return false
+	}
+	return true
+}
+
+func (p Product) PriceAt(now time.Time) Money {
+	if p.OnSale(now) {
+		return p.SalePrice
+	}
+	return p.Price
+}
+
+// PriceSchedule lists the prices the product will have from now on, one
+// entry for now and one for each upcoming start or end of the sale
+func (p Product) PriceSchedule(now time.Time) []PriceHistory {
+	schedule := []PriceHistory{{ProductId: p.ID, Price: p.PriceAt(now), EffectiveFrom: now}}
+
+	for _, at := range []*time.Time{p.SaleStartsAt, p.SaleEndsAt} {
+		if at != nil && at.After(now) {
+			schedule = append(schedule, PriceHistory{ProductId: p.ID, Price: p.PriceAt(*at), EffectiveFrom: *at})
+		}
+	}
+
+	return schedule
+}
+
+// ShippingWeight is the weight a unit is charged for in grams, the actual
+// weight or the volumetric weight of its dimensions when that is higher
+func (p Product) ShippingWeight() uint {
+	// 5000 cm3 per kg is the common volumetric divisor, the same as mm3 / 5000 in grams
+	volumetric := uint(uint64(p.Length) * uint64(p.Width) * uint64(p.Height) / 5000)
+	return max(p.Weight, volumetric)
+}
+
+// CanPublish checks the product has everything buyers need to see
+func (p Product) CanPublish() error {
+	if !p.Price.IsPositive() {
+		return errors.New("price must be greater than 0")
+	}
+	if p.CategoryId == 0 {
+		return errors.New("product needs a category")
+	}
+	if len(p.Images) == 0 && len(p.ImageUrl) == 0 {
+		return errors.New("product needs at least one image")
+	}
+	return nil
+}
+
+func (p Product) FindVariant(id uint) (*ProductVariant, bool) {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i], true
+		}
+	}
+	return nil, false
 }
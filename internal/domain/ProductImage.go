@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+type ProductImage struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ProductId    uint      `json:"product_id" gorm:"index"`
+	Url          string    `json:"url"`
+	ThumbnailUrl string    `json:"thumbnail_url"`
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Position     int       `json:"position"`
+	CreatedAt    time.Time `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"default:current_timestamp"`
+}
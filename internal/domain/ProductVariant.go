@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// ProductOption describes one dimension a product varies in, e.g. size or colour
+type ProductOption struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ProductId uint      `json:"product_id" gorm:"index"`
+	Name      string    `json:"name"`
+	Values    []string  `json:"values" gorm:"serializer:json"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+// ProductVariant is a sellable combination of option values with its own stock
+type ProductVariant struct {
+	ID           uint              `json:"id" gorm:"primaryKey"`
+	ProductId    uint              `json:"product_id" gorm:"index"`
+	UserId       int               `json:"user_id" gorm:"uniqueIndex:idx_variant_seller_sku"`
+	Sku          string            `json:"sku" gorm:"uniqueIndex:idx_variant_seller_sku;not null"`
+	Options      map[string]string `json:"options" gorm:"serializer:json"`
+	Price        Money             `json:"price" gorm:"embedded;embeddedPrefix:price_"` // overrides the product price when greater than 0
+	DisplayPrice *Money            `json:"display_price,omitempty" gorm:"-"`            // in the currency the buyer asked for
+	Stock        uint              `json:"stock"`
+	Available    uint              `json:"available" gorm:"-"` // stock minus active reservations
+	CreatedAt    time.Time         `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt    time.Time         `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+// PriceFor returns the price of the variant, variants without their own
+// price follow the product price including its sale
+func (v ProductVariant) PriceFor(p Product, now time.Time) Money {
+	if v.Price.IsPositive() {
+		return v.Price
+	}
+	return p.PriceAt(now)
+}
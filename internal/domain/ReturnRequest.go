@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	ReturnRequested = "requested"
+	ReturnApproved  = "approved"
+	ReturnRejected  = "rejected"
+	ReturnReceived  = "received"  // back in stock, waiting for the refund
+	ReturnRefunding = "refunding" // the refund was claimed and sent to the gateway
+	ReturnRefunded  = "refunded"
+)
+
+// ReturnWindow is how long after delivery items can be returned
+const ReturnWindow = 30 * 24 * time.Hour
+
+// nextReturnStatus lists the statuses a return can move to from its status
+var nextReturnStatus = map[string][]string{
+	ReturnRequested: {ReturnApproved, ReturnRejected},
+	ReturnApproved:  {ReturnReceived},
+	ReturnReceived:  {ReturnRefunding},
+	ReturnRefunding: {ReturnRefunded},
+}
+
+// ReturnRequest is a buyer asking the seller to take back some units of an
+// order item. The refund is the share of what was paid for the item.
+type ReturnRequest struct {
+	ID                  uint       `json:"id" gorm:"primaryKey"`
+	OrderId             uint       `json:"order_id" gorm:"index"`
+	OrderItemId         uint       `json:"order_item_id" gorm:"index"`
+	ShipmentId          uint       `json:"shipment_id"`
+	UserId              uint       `json:"user_id" gorm:"index"`
+	SellerId            uint       `json:"seller_id" gorm:"index"`
+	ProductId           uint       `json:"product_id"`
+	VariantId           uint       `json:"variant_id"`
+	Name                string     `json:"name"`
+	Qty                 uint       `json:"qty"`
+	Reason              string     `json:"reason"`
+	Photos              []string   `json:"photos" gorm:"-"` // download paths, filled by SetPhotoUrls
+	PhotoKeys           []string   `json:"-" gorm:"serializer:json"`
+	Status              string     `json:"status" gorm:"default:requested"`
+	SellerNote          string     `json:"seller_note"`
+	RefundAmount        Money      `json:"refund_amount" gorm:"embedded;embeddedPrefix:refund_amount_"`
+	RefundTransactionId string     `json:"refund_transaction_id"`
+	ReceivedAt          *time.Time `json:"received_at"`
+	RefundedAt          *time.Time `json:"refunded_at"`
+	CreatedAt           time.Time  `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt           time.Time  `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+// SetPhotoUrls points the photos at their download endpoint under base, the
+// photos are kept in private storage
+func (r *ReturnRequest) SetPhotoUrls(base string) {
+	r.Photos = make([]string, len(r.PhotoKeys))
+	for i := range r.PhotoKeys {
+		r.Photos[i] = fmt.Sprintf("%v/returns/%v/photos/%v", base, r.ID, i)
+	}
+}
+
+func (r ReturnRequest) CanMoveTo(status string) bool {
+	for _, next := range nextReturnStatus[r.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+type Review struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	ProductId    uint       `json:"product_id" gorm:"uniqueIndex:idx_review_product_user"`
+	UserId       uint       `json:"user_id" gorm:"uniqueIndex:idx_review_product_user"`
+	OrderId      uint       `json:"order_id"`
+	Rating       int        `json:"rating"`
+	Comment      string     `json:"comment"`
+	Reply        string     `json:"reply"`
+	RepliedAt    *time.Time `json:"replied_at"`
+	Hidden       bool       `json:"hidden" gorm:"default:false"`
+	HiddenReason string     `json:"hidden_reason,omitempty"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"default:current_timestamp"`
+}
@@ -0,0 +1,59 @@
+package domain
+
+import "time"
+
+const (
+	ApplicationPending  = "pending"
+	ApplicationApproved = "approved"
+	ApplicationRejected = "rejected"
+)
+
+const (
+	BusinessIndividual = "individual"
+	BusinessCompany    = "company"
+)
+
+const (
+	DocumentIdentity             = "identity"
+	DocumentBusinessRegistration = "business_registration" // required for companies
+	DocumentProofOfAddress       = "proof_of_address"
+)
+
+// SellerApplication is a user asking to join the seller program. The user
+// becomes a seller once an admin approves it, a rejected user can apply again.
+type SellerApplication struct {
+	ID                 uint             `json:"id" gorm:"primaryKey"`
+	UserId             uint             `json:"user_id" gorm:"index;uniqueIndex:idx_seller_application_pending,where:status = 'pending'"` // one pending application per user
+	Status             string           `json:"status" gorm:"default:pending;index"`
+	FirstName          string           `json:"first_name"`
+	LastName           string           `json:"last_name"`
+	Phone              string           `json:"phone"` // verified phone of the user when applying
+	BusinessName       string           `json:"business_name"`
+	BusinessType       string           `json:"business_type"`
+	RegistrationNumber string           `json:"registration_number"`
+	TaxId              string           `json:"tax_id"`
+	Address            PostalAddress    `json:"address" gorm:"embedded;embeddedPrefix:address_"`
+	BankAccount        uint             `json:"bank_account"`
+	SwiftCode          string           `json:"swift_code"`
+	PaymentType        string           `json:"payment_type"`
+	Currency           string           `json:"currency" gorm:"size:3"`
+	Documents          []SellerDocument `json:"documents"`
+	ReviewedBy         uint             `json:"reviewed_by"`
+	ReviewedAt         *time.Time       `json:"reviewed_at"`
+	RejectionReason    string           `json:"rejection_reason"`
+	CreatedAt          time.Time        `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt          time.Time        `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+// SellerDocument is a file sent with an application, only admins can
+// download it
+type SellerDocument struct {
+	ID                  uint      `json:"id" gorm:"primaryKey"`
+	SellerApplicationId uint      `json:"seller_application_id" gorm:"index"`
+	Type                string    `json:"type"`
+	FileName            string    `json:"file_name"`
+	ContentType         string    `json:"content_type"`
+	Size                int64     `json:"size"`
+	StorageKey          string    `json:"-"`
+	CreatedAt           time.Time `json:"created_at" gorm:"default:current_timestamp"`
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// SellerProfile is what buyers see of a seller on their storefront
+type SellerProfile struct {
+	UserId         uint      `json:"seller_id" gorm:"primaryKey;autoIncrement:false"`
+	DisplayName    string    `json:"display_name"`
+	LogoUrl        string    `json:"logo_url"`
+	LogoKey        string    `json:"-"`
+	Description    string    `json:"description"`
+	ReturnPolicy   string    `json:"return_policy"`
+	ShippingPolicy string    `json:"shipping_policy"`
+	Rating         float64   `json:"rating" gorm:"-"` // average of visible reviews on the seller's products
+	ReviewCount    int64     `json:"review_count" gorm:"-"`
+	MemberSince    time.Time `json:"member_since" gorm:"-"`
+	CreatedAt      time.Time `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"default:current_timestamp"`
+}
@@ -0,0 +1,89 @@
+package domain
+
+import "time"
+
+// Shipment is the part of an order one seller fulfils: the seller's items
+// (order items with the same seller id), the chosen shipping method and the
+// totals of those items. Each shipment moves through the order statuses on
+// its own, the order status follows the shipment furthest behind.
+type Shipment struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	OrderId           uint       `json:"order_id" gorm:"index"`
+	SellerId          uint       `json:"seller_id" gorm:"index"`
+	Status            string     `json:"status" gorm:"default:pending"`
+	ShippingProfileId uint       `json:"shipping_profile_id"`
+	ShippingMethod    string     `json:"shipping_method"`
+	DeliveryDays      int        `json:"delivery_days"`
+	Weight            uint       `json:"weight"` // grams
+	Subtotal          Money      `json:"subtotal" gorm:"embedded;embeddedPrefix:subtotal_"`
+	Discount          Money      `json:"discount" gorm:"embedded;embeddedPrefix:discount_"`
+	Tax               Money      `json:"tax" gorm:"embedded;embeddedPrefix:tax_"`
+	ShippingPrice     Money      `json:"shipping_price" gorm:"embedded;embeddedPrefix:shipping_price_"`
+	Amount            Money      `json:"amount" gorm:"embedded;embeddedPrefix:amount_"` // subtotal minus discount plus exclusive taxes and shipping
+	Payout            Money      `json:"payout" gorm:"embedded;embeddedPrefix:payout_"` // the amount in the currency the seller is paid in
+	TrackingNumber    string     `json:"tracking_number"`
+	ShippedAt         *time.Time `json:"shipped_at"`
+	DeliveredAt       *time.Time `json:"delivered_at"`
+	CreatedAt         time.Time  `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt         time.Time  `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+// NewShipment totals the items of the seller, shipping is added with SetShipping
+func NewShipment(sellerId uint, items []OrderItem, currency string) Shipment {
+	s := Shipment{
+		SellerId:      sellerId,
+		Status:        OrderPending,
+		Subtotal:      NewMoney(0, currency),
+		Discount:      NewMoney(0, currency),
+		Tax:           NewMoney(0, currency),
+		ShippingPrice: NewMoney(0, currency),
+	}
+
+	exclusive := int64(0)
+	for _, item := range items {
+		if item.SellerId != sellerId {
+			continue
+		}
+		// all items share the currency of the order
+		s.Weight += item.Weight * item.Qty
+		s.Subtotal.Amount += item.Price.Amount * int64(item.Qty)
+		s.Discount.Amount += item.Discount.Amount
+		s.Tax.Amount += item.Tax.Amount
+		for _, tax := range item.Taxes {
+			if !tax.Inclusive {
+				exclusive += tax.Amount.Amount
+			}
+		}
+	}
+
+	s.Amount = NewMoney(s.Subtotal.Amount-s.Discount.Amount+exclusive, currency)
+	return s
+}
+
+func (s *Shipment) SetShipping(price Money) error {
+	amount, err := s.Amount.Sub(s.ShippingPrice)
+	if err != nil {
+		return err
+	}
+
+	s.Amount, err = amount.Add(price)
+	if err != nil {
+		return err
+	}
+
+	s.ShippingPrice = price
+	return nil
+}
+
+// PayoutOf converts part of the shipment amount to the currency the seller
+// is paid in, at the rate of checkout
+func (s Shipment) PayoutOf(m Money) Money {
+	if len(s.Payout.Currency) == 0 || s.Payout.Currency == m.Currency {
+		return m
+	}
+	return s.Payout.Share(m.Amount, s.Amount.Amount)
+}
+
+func (s Shipment) CanMoveTo(status string) bool {
+	return nextOrderStatus[s.Status] == status
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	ShippingFlat   = "flat"
+	ShippingWeight = "weight"
+)
+
+// ShippingProfile is a shipping method a seller offers, priced by a flat
+// rate or by weight tiers, optionally free over an order amount
+type ShippingProfile struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	UserId       uint           `json:"user_id" gorm:"index"`
+	Name         string         `json:"name"`
+	Type         string         `json:"type"`
+	Currency     string         `json:"currency" gorm:"size:3"` // of all amounts of the profile
+	FlatRate     Money          `json:"flat_rate" gorm:"embedded;embeddedPrefix:flat_rate_"`
+	Tiers        []ShippingTier `json:"tiers" gorm:"serializer:json"`
+	FreeOver     Money          `json:"free_over" gorm:"embedded;embeddedPrefix:free_over_"` // 0 is never free
+	Countries    []string       `json:"countries" gorm:"serializer:json"`                    // ships everywhere when empty
+	DeliveryDays int            `json:"delivery_days"`
+	Active       bool           `json:"active" gorm:"default:true"`
+	CreatedAt    time.Time      `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt    time.Time      `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+// ShippingTier prices parcels up to a weight in grams
+type ShippingTier struct {
+	UpTo uint  `json:"up_to"`
+	Rate Money `json:"rate"`
+}
+
+// Quote prices a parcel of the given weight in grams to a country, the
+// subtotal must be in the currency of the profile
+func (p ShippingProfile) Quote(country string, weight uint, subtotal Money) (Money, error) {
+	if !p.Active {
+		return Money{}, errors.New("shipping method is not available")
+	}
+
+	if len(p.Countries) > 0 && !slices.Contains(p.Countries, country) {
+		return Money{}, fmt.Errorf("%v does not ship to %v", p.Name, country)
+	}
+
+	if p.FreeOver.IsPositive() {
+		if cmp, err := subtotal.Cmp(p.FreeOver); err == nil && cmp >= 0 {
+			return NewMoney(0, p.Currency), nil
+		}
+	}
+
+	switch p.Type {
+	case ShippingFlat:
+		return p.FlatRate, nil
+	case ShippingWeight:
+		// tiers are kept in ascending order of weight
+		for _, tier := range p.Tiers {
+			if weight <= tier.UpTo {
+				return tier.Rate, nil
+			}
+		}
+		return Money{}, fmt.Errorf("%v does not ship parcels of %vg", p.Name, weight)
+	}
+
+	return Money{}, errors.New("shipping method is not valid")
+}
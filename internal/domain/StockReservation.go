@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+const (
+	ReservationActive    = "active"
+	ReservationConverted = "converted"
+	ReservationReleased  = "released"
+)
+
+// StockReservation holds units of a product for an order between checkout
+// and payment so they can not be sold to someone else in the meantime
+type StockReservation struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	OrderId   uint      `json:"order_id" gorm:"index"`
+	UserId    uint      `json:"user_id"`
+	ProductId uint      `json:"product_id" gorm:"index"`
+	VariantId uint      `json:"variant_id"`
+	Qty       uint      `json:"qty"`
+	Status    string    `json:"status" gorm:"index;default:active"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:current_timestamp"`
+}
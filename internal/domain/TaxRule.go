@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+const TaxClassStandard = "standard"
+
+// TaxRule is a tax charged on products of a tax class shipped to a country,
+// or to one region of it. Every matching rule applies, so a country rule
+// and a region rule add up (e.g. a federal and a state tax).
+type TaxRule struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name"`                                 // shown on the order, e.g. VAT
+	Country   string    `json:"country" gorm:"size:2;index;not null"` // ISO 3166-1 alpha-2
+	Region    string    `json:"region"`                               // empty for the whole country
+	TaxClass  string    `json:"tax_class" gorm:"default:standard"`    // products of this class are taxed
+	Rate      float64   `json:"rate"`                                 // percent
+	Inclusive bool      `json:"inclusive"`                            // prices already include this tax
+	Active    bool      `json:"active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+// OrderItemTax is one tax charged on an order line
+type OrderItemTax struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	OrderItemId uint      `json:"order_item_id" gorm:"index"`
+	TaxRuleId   uint      `json:"tax_rule_id"`
+	Name        string    `json:"name"`
+	Rate        float64   `json:"rate"`
+	Inclusive   bool      `json:"inclusive"`
+	Amount      Money     `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	CreatedAt   time.Time `json:"created_at" gorm:"default:current_timestamp"`
+}
+
+func (r TaxRule) Matches(country string, region string, taxClass string) bool {
+	if len(taxClass) == 0 {
+		taxClass = TaxClassStandard
+	}
+	return r.Active && r.Country == country && r.TaxClass == taxClass &&
+		(len(r.Region) == 0 || r.Region == region)
+}
+
+// CalculateTaxes works out the taxes on a line amount. Inclusive taxes are
+// taken out of the amount, exclusive taxes are charged on top of the amount
+// without inclusive taxes.
+func CalculateTaxes(amount Money, rules []*TaxRule) []OrderItemTax {
+	var inclusiveRate float64
+	for _, r := range rules {
+		if r.Inclusive {
+			inclusiveRate += r.Rate
+		}
+	}
+
+	net := Money{Amount: int64(math.Round(float64(amount.Amount) * 100 / (100 + inclusiveRate))), Currency: amount.Currency}
+	included := amount.Amount - net.Amount
+
+	var taxes []OrderItemTax
+	var lastInclusive int
+	for _, r := range rules {
+		tax := OrderItemTax{
+			TaxRuleId: r.ID,
+			Name:      r.Name,
+			Rate:      r.Rate,
+			Inclusive: r.Inclusive,
+			Amount:    net.Percent(r.Rate),
+		}
+		if r.Inclusive {
+			included -= tax.Amount.Amount
+			lastInclusive = len(taxes)
+		}
+		taxes = append(taxes, tax)
+	}
+
+	// the inclusive taxes must add up to what was taken out of the amount
+	if inclusiveRate > 0 {
+		taxes[lastInclusive].Amount.Amount += included
+	}
+
+	return taxes
+}
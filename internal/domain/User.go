@@ -5,19 +5,22 @@ import "time"
 const (
 	SELLER = "seller"
 	BUYER  = "buyer"
+	ADMIN  = "admin" // only assigned directly in the database
 )
 
 type User struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Email     string    `json:"email" gorm:"index;unique;not null"`
-	Phone     string    `json:"phone"`
-	Password  string    `json:"password"`
-	Code      int       `json:"code"`
-	Expiry    time.Time `json:"expiry"`
-	Verified  bool      `json:"verified" gorm:"default:false"`
-	UserType  string    `json:"user_type" gorm:"default:buyer"`
-	CreatedAt time.Time `json:"created_at" gorm:"default:current_timestamp"`
-	UpdatedAt time.Time `json:"updated_at" gorm:"default:current_timestamp"`
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Email         string    `json:"email" gorm:"index;unique;not null"`
+	Phone         string    `json:"phone"`
+	Password      string    `json:"password"`
+	Code          int       `json:"code"`
+	Expiry        time.Time `json:"expiry"`
+	Verified      bool      `json:"verified" gorm:"default:false"`
+	UserType      string    `json:"user_type" gorm:"default:buyer"`
+	Currency      string    `json:"currency" gorm:"size:3"`             // sellers are paid out in this currency
+	CartReminders bool      `json:"cart_reminders" gorm:"default:true"` // false once the user opts out
+	CreatedAt     time.Time `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"default:current_timestamp"`
 }
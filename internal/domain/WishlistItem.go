@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// WishlistItem is a product, or one variant of it, a buyer saved for later.
+// The price and availability seen last are kept so the buyer can be told
+// when the price drops or the product comes back in stock.
+type WishlistItem struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	UserId          uint      `json:"user_id" gorm:"uniqueIndex:idx_wishlist_item"`
+	ProductId       uint      `json:"product_id" gorm:"uniqueIndex:idx_wishlist_item"`
+	VariantId       uint      `json:"variant_id" gorm:"uniqueIndex:idx_wishlist_item"`
+	NotifyPriceDrop bool      `json:"notify_price_drop"`
+	NotifyInStock   bool      `json:"notify_in_stock"`
+	LastPrice       Money     `json:"last_price" gorm:"embedded;embeddedPrefix:last_price_"`
+	WasAvailable    bool      `json:"-"`
+	Product         *Product  `json:"product,omitempty" gorm:"-"` // current price and stock, filled when listing
+	CreatedAt       time.Time `json:"created_at" gorm:"default:current_timestamp"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"default:current_timestamp"`
+}
+
+// Watched tells if the buyer wants to hear about changes to the product
+func (w WishlistItem) Watched() bool {
+	return w.NotifyPriceDrop || w.NotifyInStock
+}
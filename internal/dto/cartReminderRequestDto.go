@@ -0,0 +1,19 @@
+package dto
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"time"
+)
+
+type CartRemindersRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// CartRecoveryReport sums up the cart reminders sent since a date
+type CartRecoveryReport struct {
+	Since            time.Time      `json:"since"`
+	Sent             int64          `json:"sent"`
+	Recovered        int64          `json:"recovered"`
+	RecoveryRate     float64        `json:"recovery_rate"` // percent of reminders followed by a paid order
+	RecoveredRevenue []domain.Money `json:"recovered_revenue"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import "go-ecommerce-app/internal/domain"
+
+type CreateCartRequest struct {
+	ProductId uint `json:"product_id"`
+	VariantId uint `json:"variant_id"`
+	Qty       uint `json:"qty"`
+}
+
+type ApplyCouponRequest struct {
+	Code string `json:"code"`
+}
+
+type CartSummary struct {
+	Subtotal    domain.Money `json:"subtotal"`
+	Discount    domain.Money `json:"discount"`
+	Tax         domain.Money `json:"tax"` // only known once the country is given
+	Total       domain.Money `json:"total"`
+	CouponCode  string       `json:"coupon_code,omitempty"`
+	CouponError string       `json:"coupon_error,omitempty"` // why the applied coupon does not give a discount
+}
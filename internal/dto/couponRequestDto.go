@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"go-ecommerce-app/internal/domain"
+	"time"
+)
+
+type CreateCouponRequest struct {
+	Code         string       `json:"code"`
+	Type         string       `json:"type"`
+	Percent      float64      `json:"percent"`
+	Amount       domain.Money `json:"amount"`
+	MinOrder     domain.Money `json:"min_order"`
+	ExpiresAt    *time.Time   `json:"expires_at"`
+	UsageLimit   int          `json:"usage_limit"`
+	PerUserLimit int          `json:"per_user_limit"`
+	CategoryIds  []uint       `json:"category_ids"`
+	SellerIds    []uint       `json:"seller_ids"`
+	Active       *bool        `json:"active"`
+}
+
+// EditCouponRequest only changes the fields that are sent, so limits can be
+// set back to 0
+type EditCouponRequest struct {
+	Type         string        `json:"type"`
+	Percent      *float64      `json:"percent"`
+	Amount       *domain.Money `json:"amount"`
+	MinOrder     *domain.Money `json:"min_order"`
+	ExpiresAt    *time.Time    `json:"expires_at"`
+	NoExpiry     bool          `json:"no_expiry"` // removes the expiry date
+	UsageLimit   *int          `json:"usage_limit"`
+	PerUserLimit *int          `json:"per_user_limit"`
+	CategoryIds  []uint        `json:"category_ids"`
+	SellerIds    []uint        `json:"seller_ids"`
+	Active       *bool         `json:"active"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+// UpdateExchangeRatesRequest maps currency codes to how many units of that
+// currency one unit of the store currency buys
+type UpdateExchangeRatesRequest struct {
+	Rates map[string]float64 `json:"rates"`
+}
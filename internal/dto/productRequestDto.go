@@ -1,14 +1,86 @@
 package dto
 
+import (
+	"go-ecommerce-app/internal/domain"
+	"time"
+)
+
 type CreateProductRequest struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	CategoryId  uint    `json:"category_id"`
-	ImageUrl    string  `json:"image_url"`
-	Price       float64 `json:"price"`
-	Stock       uint    `json:"stock"`
+	Sku         string       `json:"sku"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	CategoryId  uint         `json:"category_id"`
+	ImageUrl    string       `json:"image_url"`
+	Price       domain.Money `json:"price"`
+	Stock       uint         `json:"stock"`
+	TaxClass    string       `json:"tax_class"` // standard when empty
+	Weight      uint         `json:"weight"`    // grams
+	Length      uint         `json:"length"`    // millimetres
+	Width       uint         `json:"width"`
+	Height      uint         `json:"height"`
 }
 
+// UpdateStockRequest changes the stock relative to the current amount,
+// use a negative change to take units out
 type UpdateStockRequest struct {
-	Stock int `json:"stock"`
+	Change    int    `json:"change"`
+	Reason    string `json:"reason"`
+	Note      string `json:"note"`
+	VariantId uint   `json:"variant_id"`
+}
+
+type ProductStatusRequest struct {
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+type ReorderThresholdRequest struct {
+	Threshold uint `json:"threshold"`
+}
+
+// ProductSaleRequest without a start begins the sale right away, without an
+// end it runs until removed
+type ProductSaleRequest struct {
+	SalePrice domain.Money `json:"sale_price"`
+	StartsAt  *time.Time   `json:"starts_at"`
+	EndsAt    *time.Time   `json:"ends_at"`
+}
+
+type ProductOptionRequest struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+type SetProductOptionsRequest struct {
+	Options []ProductOptionRequest `json:"options"`
+}
+
+type CreateVariantRequest struct {
+	Sku     string            `json:"sku"`
+	Options map[string]string `json:"options"`
+	Price   domain.Money      `json:"price"`
+	Stock   uint              `json:"stock"`
+}
+
+type ReorderImagesRequest struct {
+	ImageIds []uint `json:"image_ids"`
+}
+
+type ProductImportRowError struct {
+	Row    int      `json:"row"`
+	Sku    string   `json:"sku"`
+	Errors []string `json:"errors"`
+}
+
+type ProductImportResult struct {
+	DryRun  bool                    `json:"dry_run"`
+	Rows    int                     `json:"rows"`
+	Created int                     `json:"created"`
+	Updated int                     `json:"updated"`
+	Errors  []ProductImportRowError `json:"errors"`
+}
+
+type PurgeResult struct {
+	Products   int   `json:"products"`
+	Categories int64 `json:"categories"`
 }
@@ -0,0 +1,14 @@
+package dto
+
+// CreateReturnRequest is sent as multipart form data, with the photos as
+// files in the photos field
+type CreateReturnRequest struct {
+	OrderItemId uint   `json:"order_item_id" form:"order_item_id"`
+	Qty         uint   `json:"qty" form:"qty"`
+	Reason      string `json:"reason" form:"reason"`
+}
+
+type ReviewReturnRequest struct {
+	Status string `json:"status"` // approved or rejected
+	Note   string `json:"note"`
+}
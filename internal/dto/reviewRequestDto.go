@@ -0,0 +1,15 @@
+package dto
+
+type CreateReviewRequest struct {
+	Rating  int    `json:"rating"`
+	Comment string `json:"comment"`
+}
+
+type ReviewReplyRequest struct {
+	Reply string `json:"reply"`
+}
+
+type HideReviewRequest struct {
+	Hidden bool   `json:"hidden"`
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+type SellerProfileRequest struct {
+	DisplayName    string `json:"display_name"`
+	Description    string `json:"description"`
+	ReturnPolicy   string `json:"return_policy"`
+	ShippingPolicy string `json:"shipping_policy"`
+}
@@ -0,0 +1,61 @@
+package dto
+
+import "go-ecommerce-app/internal/domain"
+
+type ShippingProfileRequest struct {
+	Name         string                `json:"name"`
+	Type         string                `json:"type"`
+	Currency     string                `json:"currency"`
+	FlatRate     domain.Money          `json:"flat_rate"`
+	Tiers        []domain.ShippingTier `json:"tiers"`
+	FreeOver     domain.Money          `json:"free_over"`
+	Countries    []string              `json:"countries"`
+	DeliveryDays int                   `json:"delivery_days"`
+	Active       *bool                 `json:"active"`
+}
+
+// EditShippingProfileRequest only changes the fields that are sent, so the
+// flat rate and the free shipping threshold can be set back to 0
+type EditShippingProfileRequest struct {
+	Name         string                `json:"name"`
+	Type         string                `json:"type"`
+	Currency     string                `json:"currency"`
+	FlatRate     *domain.Money         `json:"flat_rate"`
+	Tiers        []domain.ShippingTier `json:"tiers"`
+	FreeOver     *domain.Money         `json:"free_over"`
+	Countries    []string              `json:"countries"`
+	DeliveryDays int                   `json:"delivery_days"`
+	Active       *bool                 `json:"active"`
+}
+
+type AddressRequest struct {
+	Name     string `json:"name"`
+	Line1    string `json:"line1"`
+	Line2    string `json:"line2"`
+	City     string `json:"city"`
+	PostCode string `json:"post_code"`
+	Region   string `json:"region"`
+	Country  string `json:"country"`
+	Phone    string `json:"phone"`
+}
+
+// CreateOrderRequest needs one shipping profile for each seller in the cart
+type CreateOrderRequest struct {
+	AddressId          uint   `json:"address_id"`
+	ShippingProfileIds []uint `json:"shipping_profile_ids"`
+}
+
+type ShippingOption struct {
+	ProfileId    uint         `json:"profile_id"`
+	Name         string       `json:"name"`
+	DeliveryDays int          `json:"delivery_days"`
+	Price        domain.Money `json:"price"`
+}
+
+// SellerShippingRates are the ways the cart items of one seller can be shipped
+type SellerShippingRates struct {
+	SellerId uint             `json:"seller_id"`
+	Weight   uint             `json:"weight"`
+	Subtotal domain.Money     `json:"subtotal"`
+	Options  []ShippingOption `json:"options"`
+}
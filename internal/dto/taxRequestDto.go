@@ -0,0 +1,29 @@
+package dto
+
+type CreateTaxRuleRequest struct {
+	Name      string  `json:"name"`
+	Country   string  `json:"country"`
+	Region    string  `json:"region"`
+	TaxClass  string  `json:"tax_class"`
+	Rate      float64 `json:"rate"`
+	Inclusive *bool   `json:"inclusive"`
+	Active    *bool   `json:"active"`
+}
+
+// EditTaxRuleRequest only changes the fields that are sent, so the rate can
+// be set to 0
+type EditTaxRuleRequest struct {
+	Name      string   `json:"name"`
+	Country   string   `json:"country"`
+	Region    string   `json:"region"`
+	TaxClass  string   `json:"tax_class"`
+	Rate      *float64 `json:"rate"`
+	Inclusive *bool    `json:"inclusive"`
+	Active    *bool    `json:"active"`
+}
+
+// ShippingLocation is where an order goes, it decides which taxes apply
+type ShippingLocation struct {
+	Country string `json:"country" query:"country"`
+	Region  string `json:"region" query:"region"`
+}
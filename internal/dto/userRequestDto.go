@@ -1,8 +1,9 @@
 package dto
 
 type UserLogin struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	CartToken string `json:"cart_token"` // guest cart to merge into the user's cart
 }
 
 type UserSignUp struct {
@@ -14,11 +15,39 @@ type VerificationCodeInput struct {
 	Code int `json:"code"`
 }
 
+// SellerInput is sent as multipart form data, with the documents as files
+// in the identity, business_registration and proof_of_address fields
 type SellerInput struct {
-	FirstName         string `json:"first_name"`
-	LastName          string `json:"last_name"`
-	PhoneNumber       string `json:"phone_number"`
-	BankAccountNumber uint   `json:"bankAccountNumber"`
-	SwiftCode         string `json:"swiftCode"`
-	PaymentType       string `json:"paymentType"`
+	FirstName          string `json:"first_name" form:"first_name"`
+	LastName           string `json:"last_name" form:"last_name"`
+	PhoneNumber        string `json:"phone_number" form:"phone_number"` // must be the verified phone
+	BusinessName       string `json:"business_name" form:"business_name"`
+	BusinessType       string `json:"business_type" form:"business_type"` // individual or company
+	RegistrationNumber string `json:"registration_number" form:"registration_number"`
+	TaxId              string `json:"tax_id" form:"tax_id"`
+	AddressLine1       string `json:"address_line1" form:"address_line1"`
+	AddressLine2       string `json:"address_line2" form:"address_line2"`
+	City               string `json:"city" form:"city"`
+	PostCode           string `json:"post_code" form:"post_code"`
+	Region             string `json:"region" form:"region"`
+	Country            string `json:"country" form:"country"`
+	BankAccountNumber  uint   `json:"bankAccountNumber" form:"bankAccountNumber"`
+	SwiftCode          string `json:"swiftCode" form:"swiftCode"`
+	PaymentType        string `json:"paymentType" form:"paymentType"`
+	Currency           string `json:"currency" form:"currency"` // payout currency, the store currency when empty
+}
+
+// ReviewSellerApplicationRequest carries the reason an application is
+// rejected, it is sent to the applicant
+type ReviewSellerApplicationRequest struct {
+	Reason string `json:"reason"`
+}
+
+type UpdateOrderStatusRequest struct {
+	Status         string `json:"status"`
+	TrackingNumber string `json:"tracking_number"` // kept when shipped
+}
+
+type PaymentRequest struct {
+	PaymentToken string `json:"payment_token"`
 }
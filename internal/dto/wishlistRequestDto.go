@@ -0,0 +1,19 @@
+package dto
+
+type WishlistRequest struct {
+	ProductId       uint `json:"product_id"`
+	VariantId       uint `json:"variant_id"`
+	NotifyPriceDrop bool `json:"notify_price_drop"`
+	NotifyInStock   bool `json:"notify_in_stock"`
+}
+
+// MoveToCartRequest adds qty units, 1 when empty, to the cart
+type MoveToCartRequest struct {
+	Qty uint `json:"qty"`
+}
+
+// SaveForLaterRequest moves a cart line to the wishlist
+type SaveForLaterRequest struct {
+	ProductId uint `json:"product_id"`
+	VariantId uint `json:"variant_id"`
+}
@@ -1,6 +1,9 @@
 package helper
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"go-ecommerce-app/internal/domain"
@@ -117,6 +120,9 @@ func (a *Auth) VerifyToken(t string) (domain.User, error) {
 
 func (a *Auth) Authorize(ctx *fiber.Ctx) error {
 	authHeader := ctx.GetReqHeaders()["Authorization"]
+	if len(authHeader) == 0 {
+		return missingToken(ctx)
+	}
 	user, err := a.VerifyToken(authHeader[0])
 	if err == nil && user.ID > 0 {
 		ctx.Locals("user", user)
@@ -129,17 +135,54 @@ func (a *Auth) Authorize(ctx *fiber.Ctx) error {
 	}
 }
 
+func missingToken(ctx *fiber.Ctx) error {
+	return ctx.Status(401).JSON(&fiber.Map{
+		"message": "authorization failed",
+		"reason":  "authorization header is missing",
+	})
+}
+
 func (a *Auth) GetCurrentUser(ctx *fiber.Ctx) domain.User {
 	user := ctx.Locals("user")
 	return user.(domain.User)
 }
 
+// GenerateCartToken creates the token of a guest cart, a random cart id
+// signed with the app secret
+func (a *Auth) GenerateCartToken() (string, error) {
+	id, err := RandomHex(16)
+	if err != nil {
+		return "", errors.New("unable to create a cart token")
+	}
+
+	return id + "." + a.signCartId(id), nil
+}
+
+// VerifyCartToken returns the guest cart id of a token made by GenerateCartToken
+func (a *Auth) VerifyCartToken(t string) (string, error) {
+	id, signature, ok := strings.Cut(t, ".")
+	if !ok || len(id) == 0 || !hmac.Equal([]byte(signature), []byte(a.signCartId(id))) {
+		return "", errors.New("invalid cart token")
+	}
+
+	return id, nil
+}
+
+func (a *Auth) signCartId(id string) string {
+	mac := hmac.New(sha256.New, []byte(a.Secret))
+	mac.Write([]byte("cart:" + id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 func (a *Auth) GenerateCode() (int, error) {
 	return RandomNumbers(6)
 }
 
 func (a *Auth) AuthorizeSeller(ctx *fiber.Ctx) error {
 	authHeader := ctx.GetReqHeaders()["Authorization"]
+	if len(authHeader) == 0 {
+		return missingToken(ctx)
+	}
 	user, err := a.VerifyToken(authHeader[0])
 
 	if err != nil {
@@ -157,3 +200,26 @@ func (a *Auth) AuthorizeSeller(ctx *fiber.Ctx) error {
 		})
 	}
 }
+
+func (a *Auth) AuthorizeAdmin(ctx *fiber.Ctx) error {
+	authHeader := ctx.GetReqHeaders()["Authorization"]
+	if len(authHeader) == 0 {
+		return missingToken(ctx)
+	}
+	user, err := a.VerifyToken(authHeader[0])
+
+	if err != nil {
+		return ctx.Status(401).JSON(&fiber.Map{
+			"message": "authorization failed",
+			"reason":  err,
+		})
+	} else if user.ID > 0 && user.UserType == domain.ADMIN {
+		ctx.Locals("user", user)
+		return ctx.Next()
+	} else {
+		return ctx.Status(401).JSON(&fiber.Map{
+			"message": "authorization failed",
+			"reason":  "only admins can access this resource",
+		})
+	}
+}
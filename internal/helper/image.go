@@ -0,0 +1,122 @@
+package helper
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+)
+
+const (
+	MaxImageSize      = 5 << 20 // 5 MB
+	MaxImagePixels    = 25_000_000
+	ThumbnailMaxWidth = 320
+)
+
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// ValidateImage checks size and content type of an uploaded image and
+// returns its decoded form together with the detected content type
+func ValidateImage(data []byte) (image.Image, string, error) {
+	if len(data) == 0 {
+		return nil, "", errors.New("image is empty")
+	}
+
+	if len(data) > MaxImageSize {
+		return nil, "", errors.New("image must be smaller than 5 MB")
+	}
+
+	contentType := http.DetectContentType(data)
+	if _, ok := imageExtensions[contentType]; !ok {
+		return nil, "", errors.New("only jpeg, png and gif images are allowed")
+	}
+
+	// a small file can declare huge dimensions, check them before the
+	// pixels are allocated
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", errors.New("image could not be decoded")
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return nil, "", errors.New("image must be at most 25 megapixels")
+	}
+
+	var img image.Image
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+	case "image/gif":
+		img, err = gif.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, "", errors.New("image could not be decoded")
+	}
+
+	return img, contentType, nil
+}
+
+func ImageExtension(contentType string) string {
+	return imageExtensions[contentType]
+}
+
+// CreateThumbnail scales the image down to maxWidth keeping the aspect ratio,
+// each target pixel is the average of the source pixels it covers
+func CreateThumbnail(img image.Image, maxWidth int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= maxWidth {
+		return img
+	}
+
+	dstW := maxWidth
+	dstH := srcH * maxWidth / srcW
+	if dstH < 1 {
+		dstH = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := max(bounds.Min.Y+(y+1)*srcH/dstH, y0+1)
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := max(bounds.Min.X+(x+1)*srcW/dstW, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
+
+// EncodeImage writes the image as jpeg, or png when transparency may matter
+func EncodeImage(w io.Writer, img image.Image, contentType string) error {
+	if contentType == "image/jpeg" {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	}
+	return png.Encode(w, img)
+}
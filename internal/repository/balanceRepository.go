@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+type BalanceRepository interface {
+	FindSellerBalance(sellerId uint) ([]domain.Money, error)
+	FindBalanceEntries(sellerId uint, limit int, offset int) ([]*domain.BalanceEntry, int64, error)
+}
+
+type balanceRepository struct {
+	db *gorm.DB
+}
+
+func NewBalanceRepository(db *gorm.DB) BalanceRepository {
+	return &balanceRepository{
+		db: db,
+	}
+}
+
+// FindSellerBalance sums the balance entries of the seller, one total per currency
+func (r balanceRepository) FindSellerBalance(sellerId uint) ([]domain.Money, error) {
+	var totals []domain.Money
+	err := r.db.Model(&domain.BalanceEntry{}).
+		Select("SUM(amount_amount) AS amount, amount_currency AS currency").
+		Where("seller_id=?", sellerId).
+		Group("amount_currency").
+		Order("amount_currency").
+		Scan(&totals).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find balance")
+	}
+
+	return totals, nil
+}
+
+func (r balanceRepository) FindBalanceEntries(sellerId uint, limit int, offset int) ([]*domain.BalanceEntry, int64, error) {
+	var entries []*domain.BalanceEntry
+	var total int64
+
+	query := r.db.Model(&domain.BalanceEntry{}).Where("seller_id=?", sellerId).Session(&gorm.Session{})
+
+	err := query.Count(&total).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, 0, errors.New("failed to find balance entries")
+	}
+
+	err = query.Order("id desc").Limit(limit).Offset(offset).Find(&entries).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, 0, errors.New("failed to find balance entries")
+	}
+
+	return entries, total, nil
+}
+
+// creditShipments adds what the buyer paid for each shipment of the order
+// to the balance of its seller
+func creditShipments(tx *gorm.DB, orderId uint) error {
+	var shipments []domain.Shipment
+	err := tx.Where("order_id=?", orderId).Find(&shipments).Error
+	if err != nil {
+		return err
+	}
+
+	for _, s := range shipments {
+		err := tx.Create(&domain.BalanceEntry{
+			SellerId:   s.SellerId,
+			Type:       domain.BalanceSale,
+			OrderId:    s.OrderId,
+			ShipmentId: s.ID,
+			Amount:     s.PayoutOf(s.Amount),
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
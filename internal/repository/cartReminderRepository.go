@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CartReminderRepository interface {
+	FindAbandonedCarts(before time.Time, since time.Time, limit int) ([]domain.AbandonedCart, error)
+	CreateReminder(e *domain.CartReminder, message string) error
+	SetCartReminders(uId uint, enabled bool) error
+	CountReminders(sellerId uint, since time.Time) (int64, int64, error)
+	SumRecoveredRevenue(sellerId uint, since time.Time) ([]domain.Money, error)
+}
+
+type cartReminderRepository struct {
+	db *gorm.DB
+}
+
+func NewCartReminderRepository(db *gorm.DB) CartReminderRepository {
+	return &cartReminderRepository{
+		db: db,
+	}
+}
+
+// FindAbandonedCarts returns the carts of users who did not opt out, last
+// changed between since and before and not reminded about since then
+func (r cartReminderRepository) FindAbandonedCarts(before time.Time, since time.Time, limit int) ([]domain.AbandonedCart, error) {
+	var carts []domain.AbandonedCart
+	err := r.db.Raw(`
+		SELECT c.user_id, c.last_updated FROM (
+			SELECT carts.user_id, MAX(carts.updated_at) AS last_updated
+			FROM carts JOIN users ON users.id = carts.user_id
+			WHERE carts.user_id > 0 AND users.cart_reminders
+			GROUP BY carts.user_id
+		) c
+		WHERE c.last_updated < ? AND c.last_updated > ?
+			AND NOT EXISTS (SELECT 1 FROM cart_reminders r WHERE r.user_id = c.user_id AND r.cart_updated_at >= c.last_updated)
+		ORDER BY c.last_updated
+		LIMIT ?`, before, since, limit).Scan(&carts).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find abandoned carts")
+	}
+
+	return carts, nil
+}
+
+// CreateReminder records the reminder and queues its message
+func (r cartReminderRepository) CreateReminder(e *domain.CartReminder, message string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(e).Error; err != nil {
+			return err
+		}
+		return queueNotification(tx, e.UserId, message)
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to create cart reminder")
+	}
+
+	return nil
+}
+
+func (r cartReminderRepository) SetCartReminders(uId uint, enabled bool) error {
+	err := r.db.Model(&domain.User{}).Where("id=?", uId).Update("cart_reminders", enabled).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to update cart reminders")
+	}
+
+	return nil
+}
+
+// recoveredSellerItems matches the order items of the seller that were in
+// the recovered reminder
+const recoveredSellerItems = `EXISTS (SELECT 1 FROM cart_reminder_items i
+	JOIN order_items o ON o.product_id = i.product_id AND o.seller_id = i.seller_id
+	WHERE i.cart_reminder_id = cart_reminders.id AND o.order_id = cart_reminders.order_id AND i.seller_id = ?)`
+
+// CountReminders counts the reminders sent since, and how many of them were
+// recovered, about products of the seller or of all sellers when 0. For a
+// seller a reminder is only recovered when the order has their products.
+func (r cartReminderRepository) CountReminders(sellerId uint, since time.Time) (int64, int64, error) {
+	var counts struct {
+		Sent      int64
+		Recovered int64
+	}
+
+	query := r.db.Model(&domain.CartReminder{}).Where("sent_at>=?", since)
+	if sellerId > 0 {
+		query = query.
+			Select("COUNT(*) AS sent, COUNT(*) FILTER (WHERE recovered_at IS NOT NULL AND "+recoveredSellerItems+") AS recovered", sellerId).
+			Where("id IN (?)", r.db.Model(&domain.CartReminderItem{}).Select("cart_reminder_id").Where("seller_id=?", sellerId))
+	} else {
+		query = query.Select("COUNT(*) AS sent, COUNT(recovered_at) AS recovered")
+	}
+
+	err := query.Scan(&counts).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return 0, 0, errors.New("failed to count cart reminders")
+	}
+
+	return counts.Sent, counts.Recovered, nil
+}
+
+// SumRecoveredRevenue adds up the orders that recovered reminders sent
+// since, one total per currency. For a seller only their shipments with
+// products that were in the reminder count.
+func (r cartReminderRepository) SumRecoveredRevenue(sellerId uint, since time.Time) ([]domain.Money, error) {
+	recovered := r.db.Model(&domain.CartReminder{}).Select("order_id").
+		Where("sent_at>=? AND recovered_at IS NOT NULL", since)
+
+	var query *gorm.DB
+	if sellerId > 0 {
+		recovered = recovered.Where(recoveredSellerItems, sellerId)
+		query = r.db.Model(&domain.Shipment{}).Where("seller_id=? AND order_id IN (?)", sellerId, recovered)
+	} else {
+		query = r.db.Model(&domain.Order{}).Where("id IN (?)", recovered)
+	}
+
+	var totals []domain.Money
+	err := query.
+		Select("SUM(amount_amount) AS amount, amount_currency AS currency").
+		Group("amount_currency").
+		Order("amount_currency").
+		Scan(&totals).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to sum recovered revenue")
+	}
+
+	return totals, nil
+}
+
+// markCartRecovered credits the order to the reminders its buyer got
+// shortly before paying, if it has products they were reminded of
+func markCartRecovered(tx *gorm.DB, orderId uint, now time.Time) error {
+	return tx.Model(&domain.CartReminder{}).
+		Where("user_id = (?) AND recovered_at IS NULL AND sent_at>?",
+			tx.Model(&domain.Order{}).Select("user_id").Where("id=?", orderId),
+			now.Add(-domain.CartRecoveryWindow)).
+		Where(`EXISTS (SELECT 1 FROM cart_reminder_items i JOIN order_items o ON o.product_id = i.product_id
+			WHERE i.cart_reminder_id = cart_reminders.id AND o.order_id = ?)`, orderId).
+		Updates(map[string]interface{}{
+			"recovered_at": now,
+			"order_id":     orderId,
+		}).Error
+}
+
+// unmarkCartRecovered takes the credit for recovered carts back from a
+// cancelled order
+func unmarkCartRecovered(tx *gorm.DB, orderId uint) error {
+	return tx.Model(&domain.CartReminder{}).
+		Where("order_id=?", orderId).
+		Updates(map[string]interface{}{
+			"recovered_at": nil,
+			"order_id":     0,
+		}).Error
+}
@@ -4,23 +4,47 @@ import (
 	"errors"
 	"go-ecommerce-app/internal/domain"
 	"log"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CatalogRepository interface {
 	CreateCategory(e *domain.Category) error
 	FindCategories() ([]*domain.Category, error)
 	FindCategoryById(id int) (*domain.Category, error)
+	FindCategoryWithProducts(id int, limit int, offset int) (*domain.Category, error)
+	CountProductsByCategory() (map[uint]int64, error)
 	EditCategory(e *domain.Category) (*domain.Category, error)
 	DeleteCategory(id int) error
 
 	CreateProduct(e *domain.Product) error
 	FindProducts() ([]*domain.Product, error)
 	FindProductById(id int) (*domain.Product, error)
-	FindSellerProducts(id int) ([]*domain.Product, error)
+	FindSellerProducts(id int, listedOnly bool, limit int, offset int) ([]*domain.Product, int64, error)
+	FindSellerLowStockProducts(id int) ([]*domain.Product, error)
+	FindDeletedSellerProducts(id int) ([]*domain.Product, error)
+	FindProductsDueForPublish(now time.Time) ([]*domain.Product, error)
 	EditProduct(e *domain.Product) (*domain.Product, error)
 	DeleteProduct(id int) error
+	FindPurgeableProducts(before time.Time) ([]*domain.Product, error)
+	PurgeProduct(id uint) error
+	FindPurgeableCategories(before time.Time) ([]*domain.Category, error)
+	PurgeCategory(id uint) error
+	UpsertProducts(products []*domain.Product) error
+	FindPriceHistory(productId uint, since time.Time) ([]*domain.PriceHistory, error)
+
+	ReplaceProductOptions(productId uint, options []domain.ProductOption) error
+	CreateVariant(e *domain.ProductVariant) error
+	FindVariantById(id int) (*domain.ProductVariant, error)
+	EditVariant(e *domain.ProductVariant) (*domain.ProductVariant, error)
+	DeleteVariant(id int) error
+
+	CreateProductImages(images []*domain.ProductImage) error
+	FindProductImageById(id int) (*domain.ProductImage, error)
+	DeleteProductImage(id int) error
+	UpdateProductImagePositions(productId uint, ids []uint) error
 }
 
 type catalogRepository struct {
@@ -33,6 +57,23 @@ func NewCatalogRepository(db *gorm.DB) CatalogRepository {
 	}
 }
 
+// listedProducts leaves out products buyers should not find in listings
+func listedProducts(db *gorm.DB) *gorm.DB {
+	return db.Where("products.archived_at IS NULL AND products.status=?", domain.ProductPublished)
+}
+
+// withProductDetails loads everything a product page needs
+func withProductDetails(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Options", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position")
+		}).
+		Preload("Variants").
+		Preload("Images", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position, id")
+		})
+}
+
 func (c catalogRepository) CreateCategory(e *domain.Category) error {
 	err := c.db.Create(&e).Error
 
@@ -67,6 +108,56 @@ func (c catalogRepository) FindCategoryById(id int) (*domain.Category, error) {
 	return category, nil
 }
 
+func (c catalogRepository) FindCategoryWithProducts(id int, limit int, offset int) (*domain.Category, error) {
+	var category *domain.Category
+	err := c.db.Preload("Products", func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(listedProducts).Order("id").Limit(limit).Offset(offset)
+	}).First(&category, id).Error
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("category does not exist")
+	}
+
+	products := make([]*domain.Product, len(category.Products))
+	for i := range category.Products {
+		products[i] = &category.Products[i]
+	}
+
+	err = applyComputedFields(c.db, products...)
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to load category products")
+	}
+
+	return category, nil
+}
+
+// CountProductsByCategory returns the number of products directly assigned to each category
+func (c catalogRepository) CountProductsByCategory() (map[uint]int64, error) {
+	var rows []struct {
+		CategoryId uint
+		Count      int64
+	}
+
+	err := c.db.Model(&domain.Product{}).
+		Select("category_id, count(*) as count").
+		Scopes(listedProducts).
+		Group("category_id").
+		Scan(&rows).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to count category products")
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryId] = row.Count
+	}
+
+	return counts, nil
+}
+
 func (c catalogRepository) EditCategory(e *domain.Category) (*domain.Category, error) {
 	err := c.db.Save(&e).Error
 
@@ -92,7 +183,12 @@ func (c catalogRepository) DeleteCategory(id int) error {
 func (c *catalogRepository) FindProducts() ([]*domain.Product, error) {
 	var products []*domain.Product
 
-	err := c.db.Find(&products).Error
+	err := c.db.Scopes(withProductDetails, listedProducts).Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyComputedFields(c.db, products...)
 	if err != nil {
 		return nil, err
 	}
@@ -103,19 +199,85 @@ func (c *catalogRepository) FindProducts() ([]*domain.Product, error) {
 func (c *catalogRepository) FindProductById(id int) (*domain.Product, error) {
 	var product *domain.Product
 
-	err := c.db.First(&product, id).Error
+	err := c.db.Scopes(withProductDetails).First(&product, id).Error
 	if err != nil {
 		log.Println("db_err:", err)
 		return nil, errors.New("product does not exist")
 	}
 
+	err = applyComputedFields(c.db, product)
+	if err != nil {
+		return nil, err
+	}
+
 	return product, nil
 }
 
-func (c *catalogRepository) FindSellerProducts(id int) ([]*domain.Product, error) {
+// FindSellerProducts returns the products of the seller and their total,
+// only those buyers can see when listedOnly is set. A limit of 0 returns all.
+func (c *catalogRepository) FindSellerProducts(id int, listedOnly bool, limit int, offset int) ([]*domain.Product, int64, error) {
 	var products []*domain.Product
+	var total int64
+
+	query := c.db.Model(&domain.Product{}).Where("user_id=?", id)
+	if listedOnly {
+		query = query.Scopes(listedProducts)
+	}
+	query = query.Session(&gorm.Session{})
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Scopes(withProductDetails).Order("id")
+	if limit > 0 {
+		query = query.Limit(limit).Offset(offset)
+	}
+
+	err = query.Find(&products).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = applyComputedFields(c.db, products...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return products, total, nil
+}
+
+// FindDeletedSellerProducts returns the soft deleted products of the seller
+// that have not been purged yet
+func (c *catalogRepository) FindDeletedSellerProducts(id int) ([]*domain.Product, error) {
+	var products []*domain.Product
+
+	err := c.db.Unscoped().Scopes(withProductDetails).
+		Where("user_id=? AND deleted_at IS NOT NULL", id).
+		Find(&products).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find deleted products")
+	}
+
+	return products, nil
+}
+
+// FindSellerLowStockProducts returns the seller's products whose stock is
+// below their reorder threshold
+func (c *catalogRepository) FindSellerLowStockProducts(id int) ([]*domain.Product, error) {
+	var products []*domain.Product
+
+	err := c.db.Scopes(withProductDetails).
+		Where("user_id=? AND reorder_threshold > 0 AND stock < reorder_threshold", id).
+		Order("stock").
+		Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
 
-	err := c.db.Where("user_id=?", id).Find(&products).Error
+	err = applyComputedFields(c.db, products...)
 	if err != nil {
 		return nil, err
 	}
@@ -123,8 +285,38 @@ func (c *catalogRepository) FindSellerProducts(id int) ([]*domain.Product, error
 	return products, nil
 }
 
+// FindProductsDueForPublish returns drafts whose scheduled publish time has passed
+func (c *catalogRepository) FindProductsDueForPublish(now time.Time) ([]*domain.Product, error) {
+	var products []*domain.Product
+
+	err := c.db.Scopes(withProductDetails).
+		Where("status=? AND publish_at IS NOT NULL AND publish_at <= ?", domain.ProductDraft, now).
+		Find(&products).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find scheduled products")
+	}
+
+	return products, nil
+}
+
 func (c *catalogRepository) CreateProduct(e *domain.Product) error {
-	err := c.db.Create(&e).Error
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		stock := e.Stock
+		e.Stock = 0
+
+		err := tx.Create(&e).Error
+		if err != nil {
+			return err
+		}
+
+		err = addInitialStock(tx, e.ID, 0, uint(e.UserId), stock, &e.Stock)
+		if err != nil {
+			return err
+		}
+
+		return recordPriceSchedule(tx, e, time.Now())
+	})
 
 	if err != nil {
 		log.Println("db_err:", err)
@@ -135,7 +327,15 @@ func (c *catalogRepository) CreateProduct(e *domain.Product) error {
 }
 
 func (c *catalogRepository) EditProduct(e *domain.Product) (*domain.Product, error) {
-	err := c.db.Save(&e).Error
+	// options and variants are managed through their own methods, stock only
+	// through the inventory ledger and ratings by the reviews
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Omit(clause.Associations, "stock", "rating", "review_count").Save(&e).Error
+		if err != nil {
+			return err
+		}
+		return recordPriceSchedule(tx, e, time.Now())
+	})
 
 	if err != nil {
 		log.Println("db_err:", err)
@@ -146,6 +346,7 @@ func (c *catalogRepository) EditProduct(e *domain.Product) (*domain.Product, err
 }
 
 func (c *catalogRepository) DeleteProduct(id int) error {
+	// soft delete, the product stays resolvable from orders
 	err := c.db.Delete(&domain.Product{}, id).Error
 
 	if err != nil {
@@ -155,3 +356,445 @@ func (c *catalogRepository) DeleteProduct(id int) error {
 
 	return nil
 }
+
+// UpsertProducts creates products without an id and updates the others,
+// all or nothing
+func (c *catalogRepository) UpsertProducts(products []*domain.Product) error {
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		for _, p := range products {
+			var err error
+			if p.ID > 0 {
+				// unscoped so deleted products matched by sku are restored
+				err = tx.Unscoped().Omit(clause.Associations, "stock", "rating", "review_count").Save(p).Error
+				if err == nil {
+					err = importStock(tx, p)
+				}
+			} else {
+				stock := p.Stock
+				p.Stock = 0
+				err = tx.Omit(clause.Associations).Create(p).Error
+				if err == nil {
+					err = addInitialStock(tx, p.ID, 0, uint(p.UserId), stock, &p.Stock)
+				}
+			}
+			if err != nil {
+				return err
+			}
+			if err = recordPriceSchedule(tx, p, time.Now()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("importing products failed")
+	}
+
+	return nil
+}
+
+// FindPurgeableProducts returns products deleted or archived before the given
+// time which no order refers to
+func (c *catalogRepository) FindPurgeableProducts(before time.Time) ([]*domain.Product, error) {
+	var products []*domain.Product
+
+	err := c.db.Unscoped().Preload("Images").
+		Where("(deleted_at < ? OR archived_at < ?)", before, before).
+		Where("id NOT IN (?)", c.db.Model(&domain.OrderItem{}).Select("product_id")).
+		Find(&products).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find products to purge")
+	}
+
+	return products, nil
+}
+
+// PurgeProduct permanently removes a product and everything that belongs to it
+func (c *catalogRepository) PurgeProduct(id uint) error {
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{
+			&domain.ProductOption{},
+			&domain.ProductVariant{},
+			&domain.ProductImage{},
+			&domain.Cart{},
+			&domain.StockReservation{},
+			&domain.InventoryMovement{},
+			&domain.Review{},
+			&domain.PriceHistory{},
+			&domain.WishlistItem{},
+		} {
+			if err := tx.Where("product_id=?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&domain.Product{}, id).Error
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to purge product")
+	}
+
+	return nil
+}
+
+// FindPurgeableCategories returns categories deleted before the given time
+// that no product and no other category refers to any more. A parent is only
+// purged once its subcategories are gone.
+func (c *catalogRepository) FindPurgeableCategories(before time.Time) ([]*domain.Category, error) {
+	var categories []*domain.Category
+
+	err := c.db.Unscoped().
+		Where("deleted_at < ?", before).
+		Where("id NOT IN (?)", c.db.Unscoped().Model(&domain.Product{}).Select("category_id")).
+		Where("id NOT IN (?)", c.db.Unscoped().Model(&domain.Category{}).Select("parent_id")).
+		Find(&categories).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find categories to purge")
+	}
+
+	return categories, nil
+}
+
+// PurgeCategory permanently removes a deleted category
+func (c *catalogRepository) PurgeCategory(id uint) error {
+	err := c.db.Unscoped().Where("deleted_at IS NOT NULL").Delete(&domain.Category{}, id).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to purge category")
+	}
+
+	return nil
+}
+
+func (c *catalogRepository) FindPriceHistory(productId uint, since time.Time) ([]*domain.PriceHistory, error) {
+	var history []*domain.PriceHistory
+
+	err := c.db.Where("product_id=? AND effective_from >= ?", productId, since).
+		Order("effective_from").
+		Find(&history).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find price history")
+	}
+
+	return history, nil
+}
+
+func (c *catalogRepository) ReplaceProductOptions(productId uint, options []domain.ProductOption) error {
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id=?", productId).Delete(&domain.ProductOption{}).Error; err != nil {
+			return err
+		}
+		if len(options) == 0 {
+			return nil
+		}
+		return tx.Create(&options).Error
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("updating product options failed")
+	}
+
+	return nil
+}
+
+func (c *catalogRepository) CreateVariant(e *domain.ProductVariant) error {
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		stock := e.Stock
+		e.Stock = 0
+
+		err := tx.Create(&e).Error
+		if err != nil {
+			return err
+		}
+
+		err = addInitialStock(tx, e.ProductId, e.ID, uint(e.UserId), stock, &e.Stock)
+		if err != nil {
+			return err
+		}
+
+		return recordVariantPrice(tx, e, time.Now())
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("variant creation failed")
+	}
+
+	return nil
+}
+
+func (c *catalogRepository) FindVariantById(id int) (*domain.ProductVariant, error) {
+	var variant *domain.ProductVariant
+
+	err := c.db.First(&variant, id).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("variant does not exist")
+	}
+
+	return variant, nil
+}
+
+func (c *catalogRepository) EditVariant(e *domain.ProductVariant) (*domain.ProductVariant, error) {
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		// stock only changes through the inventory ledger
+		err := tx.Omit("stock").Save(&e).Error
+		if err != nil {
+			return err
+		}
+
+		return recordVariantPrice(tx, e, time.Now())
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("updating variant failed")
+	}
+
+	return e, nil
+}
+
+func (c *catalogRepository) DeleteVariant(id int) error {
+	err := c.db.Delete(&domain.ProductVariant{}, id).Error
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("error deleting variant")
+	}
+
+	return nil
+}
+
+func (c *catalogRepository) CreateProductImages(images []*domain.ProductImage) error {
+	err := c.db.Create(&images).Error
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("saving product images failed")
+	}
+
+	return nil
+}
+
+func (c *catalogRepository) FindProductImageById(id int) (*domain.ProductImage, error) {
+	var image *domain.ProductImage
+
+	err := c.db.First(&image, id).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("image does not exist")
+	}
+
+	return image, nil
+}
+
+func (c *catalogRepository) DeleteProductImage(id int) error {
+	err := c.db.Delete(&domain.ProductImage{}, id).Error
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("error deleting image")
+	}
+
+	return nil
+}
+
+// UpdateProductImagePositions orders the gallery by the position of each id in ids
+func (c *catalogRepository) UpdateProductImagePositions(productId uint, ids []uint) error {
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		for i, id := range ids {
+			err := tx.Model(&domain.ProductImage{}).
+				Where("id=? AND product_id=?", id, productId).
+				Update("position", i).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("reordering images failed")
+	}
+
+	return nil
+}
+
+// applyComputedFields fills the product fields that are not stored
+func applyComputedFields(db *gorm.DB, products ...*domain.Product) error {
+	err := applyAvailability(db, products...)
+	if err != nil {
+		return err
+	}
+
+	return applyPricing(db, time.Now(), products...)
+}
+
+// applyPricing sets the current price and the lowest price of the last 30
+// days, which includes the price that was in effect when the period started
+func applyPricing(db *gorm.DB, now time.Time, products ...*domain.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	since := now.AddDate(0, 0, -30)
+
+	type lowestPrice struct {
+		ProductId     uint
+		PriceAmount   int64
+		PriceCurrency string
+	}
+
+	var rows []lowestPrice
+	err := db.Model(&domain.PriceHistory{}).
+		Select("product_id, price_currency, min(price_amount) as price_amount").
+		Where("product_id IN ? AND variant_id=0 AND effective_from >= ? AND effective_from <= ?", ids, since, now).
+		Group("product_id, price_currency").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	var before []lowestPrice
+	err = db.Model(&domain.PriceHistory{}).
+		Select("DISTINCT ON (product_id) product_id, price_amount, price_currency").
+		Where("product_id IN ? AND variant_id=0 AND effective_from < ?", ids, since).
+		Order("product_id, effective_from desc").
+		Scan(&before).Error
+	if err != nil {
+		return err
+	}
+
+	byProduct := make(map[uint][]domain.Money)
+	for _, row := range append(rows, before...) {
+		byProduct[row.ProductId] = append(byProduct[row.ProductId], domain.NewMoney(row.PriceAmount, row.PriceCurrency))
+	}
+
+	for _, p := range products {
+		p.CurrentPrice = p.PriceAt(now)
+		p.LowestPrice30d = p.CurrentPrice
+		// prices in another currency can not be compared
+		for _, price := range byProduct[p.ID] {
+			p.LowestPrice30d = domain.MinMoney(p.LowestPrice30d, price)
+		}
+	}
+
+	return nil
+}
+
+// recordPriceSchedule replaces the planned price changes of the product with
+// its current schedule, the past history is kept as it is
+func recordPriceSchedule(tx *gorm.DB, p *domain.Product, now time.Time) error {
+	err := tx.Where("product_id=? AND variant_id=0 AND effective_from > ?", p.ID, now).Delete(&domain.PriceHistory{}).Error
+	if err != nil {
+		return err
+	}
+
+	schedule := p.PriceSchedule(now)
+
+	// no need for a new entry while the price in effect stays the same
+	var last domain.PriceHistory
+	err = tx.Where("product_id=? AND variant_id=0 AND effective_from <= ?", p.ID, now).Order("effective_from desc").Limit(1).Find(&last).Error
+	if err != nil {
+		return err
+	}
+	if last.ID > 0 && last.Price == schedule[0].Price {
+		schedule = schedule[1:]
+	}
+
+	if len(schedule) == 0 {
+		return nil
+	}
+	return tx.Create(&schedule).Error
+}
+
+// recordVariantPrice adds an entry to the price history when the price of
+// the variant changes. Variants without their own price follow the product
+// history from then on.
+func recordVariantPrice(tx *gorm.DB, v *domain.ProductVariant, now time.Time) error {
+	price := v.Price
+	if !price.IsPositive() {
+		var product domain.Product
+		err := tx.Unscoped().First(&product, v.ProductId).Error
+		if err != nil {
+			return err
+		}
+		price = product.PriceAt(now)
+	}
+
+	var last domain.PriceHistory
+	err := tx.Where("product_id=? AND variant_id=? AND effective_from <= ?", v.ProductId, v.ID, now).Order("effective_from desc").Limit(1).Find(&last).Error
+	if err != nil {
+		return err
+	}
+	if last.ID > 0 && last.Price == price {
+		return nil
+	}
+
+	return tx.Create(&domain.PriceHistory{
+		ProductId:     v.ProductId,
+		VariantId:     v.ID,
+		Price:         price,
+		EffectiveFrom: now,
+	}).Error
+}
+
+// addInitialStock records the stock a new product or variant starts with in
+// the inventory ledger
+func addInitialStock(tx *gorm.DB, productId uint, variantId uint, userId uint, stock uint, stockAfter *uint) error {
+	if stock == 0 {
+		return nil
+	}
+
+	m := &domain.InventoryMovement{
+		ProductId: productId,
+		VariantId: variantId,
+		UserId:    userId,
+		Change:    int(stock),
+		Reason:    domain.MovementRestock,
+		Note:      "initial stock",
+	}
+	err := adjustStock(tx, m)
+	if err != nil {
+		return err
+	}
+
+	*stockAfter = m.StockAfter
+	return nil
+}
+
+// importStock turns the stock set by an import into a correction of the
+// current stock
+func importStock(tx *gorm.DB, p *domain.Product) error {
+	var current domain.Product
+	err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "stock").First(&current, p.ID).Error
+	if err != nil {
+		return err
+	}
+
+	change := int(p.Stock) - int(current.Stock)
+	if change == 0 {
+		return nil
+	}
+
+	return adjustStock(tx, &domain.InventoryMovement{
+		ProductId: p.ID,
+		UserId:    uint(p.UserId),
+		Change:    change,
+		Reason:    domain.MovementCorrection,
+		Note:      "csv import",
+	})
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CouponRepository interface {
+	CreateCoupon(e *domain.Coupon) error
+	FindCoupons() ([]*domain.Coupon, error)
+	FindCouponById(id uint) (*domain.Coupon, error)
+	FindCouponByCode(code string) (*domain.Coupon, error)
+	EditCoupon(e *domain.Coupon) (*domain.Coupon, error)
+	CountRedemptions(couponId uint, uId uint) (int64, int64, error)
+
+	FindCartCoupon(uId uint) (*domain.CartCoupon, error)
+	SetCartCoupon(uId uint, couponId uint) error
+	DeleteCartCoupon(uId uint) error
+}
+
+type couponRepository struct {
+	db *gorm.DB
+}
+
+func NewCouponRepository(db *gorm.DB) CouponRepository {
+	return &couponRepository{
+		db: db,
+	}
+}
+
+func (r couponRepository) CreateCoupon(e *domain.Coupon) error {
+	err := r.db.Create(e).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("create coupon failed, the code may already exist")
+	}
+
+	return nil
+}
+
+func (r couponRepository) FindCoupons() ([]*domain.Coupon, error) {
+	var coupons []*domain.Coupon
+	err := r.db.Order("id desc").Find(&coupons).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find coupons")
+	}
+
+	return coupons, nil
+}
+
+func (r couponRepository) FindCouponById(id uint) (*domain.Coupon, error) {
+	var coupon *domain.Coupon
+	err := r.db.First(&coupon, id).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("coupon does not exist")
+	}
+
+	return coupon, nil
+}
+
+func (r couponRepository) FindCouponByCode(code string) (*domain.Coupon, error) {
+	var coupon *domain.Coupon
+	err := r.db.First(&coupon, "code=?", code).Error
+	if err != nil {
+		return nil, errors.New("coupon does not exist")
+	}
+
+	return coupon, nil
+}
+
+func (r couponRepository) EditCoupon(e *domain.Coupon) (*domain.Coupon, error) {
+	err := r.db.Save(e).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to update coupon")
+	}
+
+	return e, nil
+}
+
+// CountRedemptions returns how often the coupon was used in total and by the user
+func (r couponRepository) CountRedemptions(couponId uint, uId uint) (int64, int64, error) {
+	return countRedemptions(r.db, couponId, uId)
+}
+
+func (r couponRepository) FindCartCoupon(uId uint) (*domain.CartCoupon, error) {
+	var cartCoupon *domain.CartCoupon
+	err := r.db.First(&cartCoupon, "user_id=?", uId).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return cartCoupon, nil
+}
+
+func (r couponRepository) SetCartCoupon(uId uint, couponId uint) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"coupon_id", "updated_at"}),
+	}).Create(&domain.CartCoupon{UserId: uId, CouponId: couponId}).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to apply coupon")
+	}
+
+	return nil
+}
+
+func (r couponRepository) DeleteCartCoupon(uId uint) error {
+	return r.db.Where("user_id=?", uId).Delete(&domain.CartCoupon{}).Error
+}
+
+func countRedemptions(tx *gorm.DB, couponId uint, uId uint) (int64, int64, error) {
+	var total, byUser int64
+
+	err := tx.Model(&domain.CouponRedemption{}).Where("coupon_id=?", couponId).Count(&total).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = tx.Model(&domain.CouponRedemption{}).Where("coupon_id=? AND user_id=?", couponId, uId).Count(&byUser).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return total, byUser, nil
+}
+
+// redeemCoupon records the use of a coupon, the coupon row is locked so
+// usage limits hold with concurrent checkouts
+func redeemCoupon(tx *gorm.DB, e *domain.CouponRedemption) error {
+	var coupon domain.Coupon
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, e.CouponId).Error
+	if err != nil {
+		return errors.New("coupon does not exist")
+	}
+
+	total, byUser, err := countRedemptions(tx, coupon.ID, e.UserId)
+	if err != nil {
+		return err
+	}
+
+	if coupon.UsageLimit > 0 && total >= int64(coupon.UsageLimit) {
+		return errors.New("coupon usage limit has been reached")
+	}
+	if coupon.PerUserLimit > 0 && byUser >= int64(coupon.PerUserLimit) {
+		return errors.New("you have already used this coupon")
+	}
+
+	if err := tx.Create(e).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("user_id=?", e.UserId).Delete(&domain.CartCoupon{}).Error
+}
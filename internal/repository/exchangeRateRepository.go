@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExchangeRateRepository interface {
+	FindRates() ([]*domain.ExchangeRate, error)
+	SaveRates(rates []*domain.ExchangeRate) error
+	DeleteRate(currency string) error
+}
+
+type exchangeRateRepository struct {
+	db *gorm.DB
+}
+
+func NewExchangeRateRepository(db *gorm.DB) ExchangeRateRepository {
+	return &exchangeRateRepository{
+		db: db,
+	}
+}
+
+func (r exchangeRateRepository) FindRates() ([]*domain.ExchangeRate, error) {
+	var rates []*domain.ExchangeRate
+	err := r.db.Order("currency").Find(&rates).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find exchange rates")
+	}
+
+	return rates, nil
+}
+
+// SaveRates inserts new currencies and updates the rate of known ones
+func (r exchangeRateRepository) SaveRates(rates []*domain.ExchangeRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(&rates).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to save exchange rates")
+	}
+
+	return nil
+}
+
+// DeleteRate removes the rate of the currency, unless products are priced or
+// sellers are paid in it
+func (r exchangeRateRepository) DeleteRate(currency string) error {
+	var products, sellers int64
+	err := r.db.Model(&domain.Product{}).Where("price_currency=?", currency).Count(&products).Error
+	if err == nil {
+		err = r.db.Model(&domain.User{}).Where("currency=?", currency).Count(&sellers).Error
+	}
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to delete exchange rate")
+	}
+
+	if products > 0 || sellers > 0 {
+		return fmt.Errorf("currency %v is still used by %v products and %v sellers", currency, products, sellers)
+	}
+
+	result := r.db.Where("currency=?", currency).Delete(&domain.ExchangeRate{})
+	if result.Error != nil {
+		log.Println("db_err:", result.Error)
+		return errors.New("failed to delete exchange rate")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("exchange rate does not exist")
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrOutOfStock = errors.New("not enough stock available")
+
+type InventoryRepository interface {
+	ReleaseExpiredReservations(now time.Time) (int64, error)
+	AdjustStock(m *domain.InventoryMovement) error
+	FindProductMovements(productId uint, limit int, offset int) ([]*domain.InventoryMovement, int64, error)
+}
+
+type inventoryRepository struct {
+	db *gorm.DB
+}
+
+func NewInventoryRepository(db *gorm.DB) InventoryRepository {
+	return &inventoryRepository{
+		db: db,
+	}
+}
+
+// ReleaseExpiredReservations frees the stock held by unpaid orders whose
+// reservation window has passed and marks those orders expired
+func (r inventoryRepository) ReleaseExpiredReservations(now time.Time) (int64, error) {
+	var expired int64
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.StockReservation{}).
+			Where("status=? AND expires_at<=?", domain.ReservationActive, now).
+			Update("status", domain.ReservationReleased).Error
+		if err != nil {
+			return err
+		}
+
+		// coupons used by expired orders can be used again
+		err = tx.Where("order_id IN (?)", tx.Model(&domain.Order{}).Select("id").
+			Where("status=? AND expires_at<=?", domain.OrderPending, now)).
+			Delete(&domain.CouponRedemption{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&domain.Shipment{}).
+			Where("order_id IN (?)", tx.Model(&domain.Order{}).Select("id").
+				Where("status=? AND expires_at<=?", domain.OrderPending, now)).
+			Update("status", domain.OrderExpired).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&domain.Order{}).
+			Where("status=? AND expires_at<=?", domain.OrderPending, now).
+			Update("status", domain.OrderExpired)
+		expired = result.RowsAffected
+		return result.Error
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return 0, errors.New("failed to release expired reservations")
+	}
+
+	return expired, nil
+}
+
+func (r inventoryRepository) AdjustStock(m *domain.InventoryMovement) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		return adjustStock(tx, m)
+	})
+
+	if errors.Is(err, ErrOutOfStock) {
+		return errors.New("stock can not go below zero")
+	}
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to adjust stock")
+	}
+
+	return nil
+}
+
+func (r inventoryRepository) FindProductMovements(productId uint, limit int, offset int) ([]*domain.InventoryMovement, int64, error) {
+	var movements []*domain.InventoryMovement
+	var total int64
+
+	query := r.db.Model(&domain.InventoryMovement{}).Where("product_id=?", productId).Session(&gorm.Session{})
+
+	err := query.Count(&total).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, 0, errors.New("failed to find stock movements")
+	}
+
+	err = query.Order("id desc").Limit(limit).Offset(offset).Find(&movements).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, 0, errors.New("failed to find stock movements")
+	}
+
+	return movements, total, nil
+}
+
+// reserveStock creates the reservation if the product, or its variant, has
+// enough stock that is not already reserved. The product row is locked so
+// concurrent checkouts of the same product are serialized.
+func reserveStock(tx *gorm.DB, e *domain.StockReservation) error {
+	var product domain.Product
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, e.ProductId).Error
+	if err != nil {
+		return errors.New("product does not exist")
+	}
+
+	stock := product.Stock
+	if e.VariantId > 0 {
+		var variant domain.ProductVariant
+		err = tx.Where("id=? AND product_id=?", e.VariantId, e.ProductId).First(&variant).Error
+		if err != nil {
+			return errors.New("variant does not exist")
+		}
+		stock = variant.Stock
+	}
+
+	reserved, err := reservedQty(tx, e.ProductId, e.VariantId)
+	if err != nil {
+		return err
+	}
+
+	if stock < reserved+e.Qty {
+		return ErrOutOfStock
+	}
+
+	return tx.Create(e).Error
+}
+
+// convertReservations turns the active reservations of a paid order into
+// stock movements
+func convertReservations(tx *gorm.DB, orderId uint, now time.Time) error {
+	var reservations []domain.StockReservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id=? AND status=?", orderId, domain.ReservationActive).
+		Find(&reservations).Error
+	if err != nil {
+		return err
+	}
+
+	if len(reservations) == 0 {
+		return errors.New("order has no reserved stock")
+	}
+
+	for _, r := range reservations {
+		if !r.ExpiresAt.After(now) {
+			return errors.New("stock reservation has expired")
+		}
+
+		err := adjustStock(tx, &domain.InventoryMovement{
+			ProductId: r.ProductId,
+			VariantId: r.VariantId,
+			OrderId:   orderId,
+			Change:    -int(r.Qty),
+			Reason:    domain.MovementSale,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&domain.StockReservation{}).
+		Where("order_id=? AND status=?", orderId, domain.ReservationActive).
+		Update("status", domain.ReservationConverted).Error
+}
+
+// adjustStock applies the change of the movement to the variant, if any,
+// and the product and records the movement. The stock never goes below zero.
+func adjustStock(tx *gorm.DB, m *domain.InventoryMovement) error {
+	if m.VariantId > 0 {
+		var variant domain.ProductVariant
+		result := tx.Model(&variant).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "stock"}}}).
+			Where("id=? AND product_id=? AND stock + ? >= 0", m.VariantId, m.ProductId, m.Change).
+			UpdateColumn("stock", gorm.Expr("stock + ?", m.Change))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOutOfStock
+		}
+		m.StockAfter = variant.Stock
+	}
+
+	var product domain.Product
+	result := tx.Model(&product).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "stock"}, {Name: "reorder_threshold"}, {Name: "user_id"}, {Name: "name"}}}).
+		Where("id=? AND stock + ? >= 0", m.ProductId, m.Change).
+		UpdateColumn("stock", gorm.Expr("stock + ?", m.Change))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOutOfStock
+	}
+
+	if m.VariantId == 0 {
+		m.StockAfter = product.Stock
+	}
+
+	// alert the seller once, when the stock crosses the reorder threshold
+	before := int(product.Stock) - m.Change
+	if product.ReorderThreshold > 0 && product.Stock < product.ReorderThreshold && before >= int(product.ReorderThreshold) {
+		msg := fmt.Sprintf("Low stock: %v has %v units left (reorder threshold %v)", product.Name, product.Stock, product.ReorderThreshold)
+		if err := queueNotification(tx, uint(product.UserId), msg); err != nil {
+			return err
+		}
+	}
+
+	return tx.Create(m).Error
+}
+
+func reservedQty(tx *gorm.DB, productId uint, variantId uint) (uint, error) {
+	var reserved uint
+	err := tx.Model(&domain.StockReservation{}).
+		Select("coalesce(sum(qty), 0)").
+		Where("product_id=? AND variant_id=? AND status=? AND expires_at>?", productId, variantId, domain.ReservationActive, time.Now()).
+		Scan(&reserved).Error
+	return reserved, err
+}
+
+// applyAvailability sets the available to sell quantity of the products and
+// their variants, which is the stock minus active reservations
+func applyAvailability(db *gorm.DB, products ...*domain.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	var rows []struct {
+		ProductId uint
+		VariantId uint
+		Qty       uint
+	}
+	err := db.Model(&domain.StockReservation{}).
+		Select("product_id, variant_id, sum(qty) as qty").
+		Where("product_id IN ? AND status=? AND expires_at>?", ids, domain.ReservationActive, time.Now()).
+		Group("product_id, variant_id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	type key struct{ productId, variantId uint }
+	reserved := make(map[key]uint, len(rows))
+	byProduct := make(map[uint]uint)
+	for _, row := range rows {
+		reserved[key{row.ProductId, row.VariantId}] = row.Qty
+		byProduct[row.ProductId] += row.Qty
+	}
+
+	available := func(stock uint, held uint) uint {
+		if held >= stock {
+			return 0
+		}
+		return stock - held
+	}
+
+	for _, p := range products {
+		p.Available = available(p.Stock, byProduct[p.ID])
+		for i := range p.Variants {
+			v := &p.Variants[i]
+			v.Available = available(v.Stock, reserved[key{p.ID, v.ID}])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InvoiceRepository interface {
+	FindInvoiceById(id uint) (*domain.Invoice, error)
+	FindOrderInvoices(orderId uint) ([]*domain.Invoice, error)
+	FindSellerInvoices(sellerId uint, limit int, offset int) ([]*domain.Invoice, int64, error)
+	SetInvoiceFile(id uint, storageKey string) error
+}
+
+type invoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) InvoiceRepository {
+	return &invoiceRepository{
+		db: db,
+	}
+}
+
+func (r invoiceRepository) FindInvoiceById(id uint) (*domain.Invoice, error) {
+	var invoice *domain.Invoice
+	err := r.db.First(&invoice, id).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("invoice does not exist")
+	}
+
+	return invoice, nil
+}
+
+func (r invoiceRepository) FindOrderInvoices(orderId uint) ([]*domain.Invoice, error) {
+	var invoices []*domain.Invoice
+	err := r.db.Where("order_id=?", orderId).Order("id").Find(&invoices).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find invoices")
+	}
+
+	return invoices, nil
+}
+
+func (r invoiceRepository) FindSellerInvoices(sellerId uint, limit int, offset int) ([]*domain.Invoice, int64, error) {
+	var invoices []*domain.Invoice
+	var total int64
+
+	query := r.db.Model(&domain.Invoice{}).Where("seller_id=?", sellerId).Session(&gorm.Session{})
+
+	err := query.Count(&total).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, 0, errors.New("failed to find invoices")
+	}
+
+	err = query.Order("number desc").Limit(limit).Offset(offset).Find(&invoices).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, 0, errors.New("failed to find invoices")
+	}
+
+	return invoices, total, nil
+}
+
+func (r invoiceRepository) SetInvoiceFile(id uint, storageKey string) error {
+	err := r.db.Model(&domain.Invoice{}).Where("id=?", id).Update("storage_key", storageKey).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to update invoice")
+	}
+
+	return nil
+}
+
+// issueInvoices numbers an invoice for every shipment of the paid order.
+// The sequence row of the seller stays locked until the transaction ends,
+// so numbers are only used by committed invoices and never skipped.
+func issueInvoices(tx *gorm.DB, orderId uint, now time.Time) error {
+	var order domain.Order
+	err := tx.Preload("Shipments").First(&order, orderId).Error
+	if err != nil {
+		return err
+	}
+
+	for _, s := range order.Shipments {
+		number, err := nextInvoiceNumber(tx, s.SellerId)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&domain.Invoice{
+			SellerId:   s.SellerId,
+			Number:     number,
+			Code:       domain.InvoiceCode(s.SellerId, number),
+			OrderId:    orderId,
+			ShipmentId: s.ID,
+			UserId:     order.UserId,
+			Amount:     s.Amount,
+			IssuedAt:   now,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// creditInvoices cancels the invoices of a cancelled order with credit notes.
+// The pdf is rendered again to show the credit note.
+func creditInvoices(tx *gorm.DB, orderId uint, now time.Time) error {
+	var invoices []domain.Invoice
+	err := tx.Where("order_id=? AND credited_at IS NULL", orderId).Order("id").Find(&invoices).Error
+	if err != nil {
+		return err
+	}
+
+	for _, invoice := range invoices {
+		number, err := nextInvoiceNumber(tx, invoice.SellerId)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&domain.Invoice{}).Where("id=?", invoice.ID).Updates(map[string]interface{}{
+			"credit_note": domain.CreditNoteCode(invoice.SellerId, number),
+			"credited_at": now,
+			"storage_key": "",
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nextInvoiceNumber takes the next number of the seller's sequence
+func nextInvoiceNumber(tx *gorm.DB, sellerId uint) (uint, error) {
+	var number uint
+	err := tx.Raw(`INSERT INTO invoice_sequences (seller_id, last) VALUES (?, 1)
+		ON CONFLICT (seller_id) DO UPDATE SET last = invoice_sequences.last + 1
+		RETURNING last`, sellerId).Scan(&number).Error
+	return number, err
+}
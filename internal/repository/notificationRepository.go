@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	CreateNotification(e *domain.Notification) error
+	FindPendingNotifications(limit int) ([]*domain.Notification, error)
+	UpdateNotification(e *domain.Notification) error
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{
+		db: db,
+	}
+}
+
+func (r notificationRepository) CreateNotification(e *domain.Notification) error {
+	e.Status = domain.NotificationPending
+	err := r.db.Create(e).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to queue notification")
+	}
+
+	return nil
+}
+
+func (r notificationRepository) FindPendingNotifications(limit int) ([]*domain.Notification, error) {
+	var notifications []*domain.Notification
+	err := r.db.Where("status=?", domain.NotificationPending).Order("id").Limit(limit).Find(&notifications).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find notifications")
+	}
+
+	return notifications, nil
+}
+
+func (r notificationRepository) UpdateNotification(e *domain.Notification) error {
+	err := r.db.Save(e).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to update notification")
+	}
+
+	return nil
+}
+
+// queueNotification adds a message to the queue, it takes a transaction so
+// the message is only sent if the change it is about gets committed
+func queueNotification(tx *gorm.DB, userId uint, message string) error {
+	return tx.Create(&domain.Notification{
+		UserId:  userId,
+		Message: message,
+		Status:  domain.NotificationPending,
+	}).Error
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReturnRepository interface {
+	CreateReturn(e *domain.ReturnRequest) error
+	FindReturnById(id uint) (*domain.ReturnRequest, error)
+	FindUserReturns(uId uint) ([]*domain.ReturnRequest, error)
+	FindSellerReturns(sellerId uint, status string, limit int, offset int) ([]*domain.ReturnRequest, int64, error)
+	CountReturnedQty(orderItemId uint) (uint, error)
+	UpdateReturnStatus(e *domain.ReturnRequest, status string) error
+	ReceiveReturn(e *domain.ReturnRequest, now time.Time) error
+	ClaimReturnRefund(e *domain.ReturnRequest) error
+	RefundReturn(e *domain.ReturnRequest, refundId string, now time.Time) error
+}
+
+type returnRepository struct {
+	db *gorm.DB
+}
+
+func NewReturnRepository(db *gorm.DB) ReturnRepository {
+	return &returnRepository{
+		db: db,
+	}
+}
+
+// CreateReturn saves the request and lets the seller know about it. The
+// order item is locked so concurrent requests can not return more units
+// than were bought.
+func (r returnRepository) CreateReturn(e *domain.ReturnRequest) error {
+	var qtyErr error
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var item domain.OrderItem
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, e.OrderItemId).Error
+		if err != nil {
+			return err
+		}
+
+		returned, err := returnedQty(tx, item.ID)
+		if err != nil {
+			return err
+		}
+		if returned+e.Qty > item.Qty {
+			qtyErr = fmt.Errorf("up to %v units of this item can be returned", item.Qty-min(returned, item.Qty))
+			return qtyErr
+		}
+
+		if err := tx.Create(e).Error; err != nil {
+			return err
+		}
+
+		msg := fmt.Sprintf("Return requested: %v x %v of order %v", e.Qty, e.Name, e.OrderId)
+		return queueNotification(tx, e.SellerId, msg)
+	})
+
+	if qtyErr != nil {
+		return qtyErr
+	}
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to create return request")
+	}
+
+	return nil
+}
+
+func (r returnRepository) FindReturnById(id uint) (*domain.ReturnRequest, error) {
+	var e *domain.ReturnRequest
+	err := r.db.First(&e, id).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("return request does not exist")
+	}
+
+	return e, nil
+}
+
+func (r returnRepository) FindUserReturns(uId uint) ([]*domain.ReturnRequest, error) {
+	var returns []*domain.ReturnRequest
+	err := r.db.Where("user_id=?", uId).Order("id desc").Find(&returns).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find return requests")
+	}
+
+	return returns, nil
+}
+
+// FindSellerReturns returns a page of the seller's return requests, of all
+// statuses when status is empty
+func (r returnRepository) FindSellerReturns(sellerId uint, status string, limit int, offset int) ([]*domain.ReturnRequest, int64, error) {
+	var returns []*domain.ReturnRequest
+	var total int64
+
+	query := r.db.Model(&domain.ReturnRequest{}).Where("seller_id=?", sellerId)
+	if len(status) > 0 {
+		query = query.Where("status=?", status)
+	}
+	query = query.Session(&gorm.Session{})
+
+	err := query.Count(&total).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, 0, errors.New("failed to find return requests")
+	}
+
+	err = query.Order("id desc").Limit(limit).Offset(offset).Find(&returns).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, 0, errors.New("failed to find return requests")
+	}
+
+	return returns, total, nil
+}
+
+// CountReturnedQty is the number of units of the order item already asked
+// back in requests that were not rejected
+func (r returnRepository) CountReturnedQty(orderItemId uint) (uint, error) {
+	qty, err := returnedQty(r.db, orderItemId)
+	if err != nil {
+		log.Println("db_err:", err)
+		return 0, errors.New("failed to find return requests")
+	}
+
+	return qty, nil
+}
+
+func returnedQty(tx *gorm.DB, orderItemId uint) (uint, error) {
+	var qty uint
+	err := tx.Model(&domain.ReturnRequest{}).
+		Select("COALESCE(SUM(qty), 0)").
+		Where("order_item_id=? AND status<>?", orderItemId, domain.ReturnRejected).
+		Scan(&qty).Error
+	return qty, err
+}
+
+// UpdateReturnStatus moves the return on from its current status and tells
+// the buyer
+func (r returnRepository) UpdateReturnStatus(e *domain.ReturnRequest, status string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := moveReturn(tx, e, status, map[string]interface{}{
+			"seller_note": e.SellerNote,
+		})
+		if err != nil {
+			return err
+		}
+
+		msg := fmt.Sprintf("Your return of %v from order %v was %v", e.Name, e.OrderId, status)
+		return queueNotification(tx, e.UserId, msg)
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to update return request")
+	}
+
+	return nil
+}
+
+// ReceiveReturn marks the return received and puts the units back in stock
+func (r returnRepository) ReceiveReturn(e *domain.ReturnRequest, now time.Time) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := moveReturn(tx, e, domain.ReturnReceived, map[string]interface{}{
+			"received_at": now,
+		})
+		if err != nil {
+			return err
+		}
+
+		return adjustStock(tx, &domain.InventoryMovement{
+			ProductId: e.ProductId,
+			VariantId: e.VariantId,
+			UserId:    e.SellerId,
+			OrderId:   e.OrderId,
+			Change:    int(e.Qty),
+			Reason:    domain.MovementReturn,
+			Note:      fmt.Sprintf("return %v", e.ID),
+		})
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to receive return")
+	}
+
+	e.ReceivedAt = &now
+	return nil
+}
+
+// ClaimReturnRefund moves a received return to refunding before the gateway
+// is called, so concurrent attempts can not both start a refund
+func (r returnRepository) ClaimReturnRefund(e *domain.ReturnRequest) error {
+	err := moveReturn(r.db, e, domain.ReturnRefunding, map[string]interface{}{})
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("return is already being refunded")
+	}
+
+	return nil
+}
+
+// RefundReturn records the refund made through the payment gateway and
+// takes it from the seller's balance
+func (r returnRepository) RefundReturn(e *domain.ReturnRequest, refundId string, now time.Time) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := moveReturn(tx, e, domain.ReturnRefunded, map[string]interface{}{
+			"refund_transaction_id": refundId,
+			"refunded_at":           now,
+		})
+		if err != nil {
+			return err
+		}
+
+		var shipment domain.Shipment
+		err = tx.First(&shipment, e.ShipmentId).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&domain.BalanceEntry{
+			SellerId:        e.SellerId,
+			Type:            domain.BalanceRefund,
+			OrderId:         e.OrderId,
+			ShipmentId:      e.ShipmentId,
+			ReturnRequestId: e.ID,
+			Amount:          shipment.PayoutOf(domain.NewMoney(-e.RefundAmount.Amount, e.RefundAmount.Currency)),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		msg := fmt.Sprintf("Your return of %v from order %v was refunded: %v", e.Name, e.OrderId, e.RefundAmount)
+		return queueNotification(tx, e.UserId, msg)
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to record the refund")
+	}
+
+	e.RefundTransactionId = refundId
+	e.RefundedAt = &now
+	return nil
+}
+
+// moveReturn updates the return only if it is still in the status it was
+// read in, so concurrent updates can not both apply
+func moveReturn(tx *gorm.DB, e *domain.ReturnRequest, status string, updates map[string]interface{}) error {
+	updates["status"] = status
+	result := tx.Model(&domain.ReturnRequest{}).
+		Where("id=? AND status=?", e.ID, e.Status).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("return request was changed in the meantime")
+	}
+
+	e.Status = status
+	return nil
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+type ReviewRepository interface {
+	CreateReview(e *domain.Review) error
+	FindReviewById(id uint) (*domain.Review, error)
+	FindProductReviews(productId uint, limit int, offset int) ([]*domain.Review, int64, error)
+	UpdateReview(e *domain.Review) (*domain.Review, error)
+
+	FindDeliveredOrderItem(uId uint, productId uint) (*domain.OrderItem, error)
+}
+
+type reviewRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository(db *gorm.DB) ReviewRepository {
+	return &reviewRepository{
+		db: db,
+	}
+}
+
+func (r reviewRepository) CreateReview(e *domain.Review) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(e).Error; err != nil {
+			return err
+		}
+		return refreshProductRating(tx, e.ProductId)
+	})
+
+	if isUniqueViolation(err) {
+		return errors.New("you have already reviewed this product")
+	}
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("create review failed")
+	}
+
+	return nil
+}
+
+func (r reviewRepository) FindReviewById(id uint) (*domain.Review, error) {
+	var review *domain.Review
+	err := r.db.First(&review, id).Error
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("review does not exist")
+	}
+
+	return review, nil
+}
+
+// FindProductReviews returns a page of the visible reviews of a product and
+// the number of visible reviews in total
+func (r reviewRepository) FindProductReviews(productId uint, limit int, offset int) ([]*domain.Review, int64, error) {
+	var reviews []*domain.Review
+	var total int64
+
+	query := r.db.Model(&domain.Review{}).Where("product_id=? AND hidden=false", productId).Session(&gorm.Session{})
+
+	err := query.Count(&total).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, 0, errors.New("failed to find reviews")
+	}
+
+	err = query.Order("id desc").Limit(limit).Offset(offset).Find(&reviews).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, 0, errors.New("failed to find reviews")
+	}
+
+	return reviews, total, nil
+}
+
+func (r reviewRepository) UpdateReview(e *domain.Review) (*domain.Review, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(e).Error; err != nil {
+			return err
+		}
+		return refreshProductRating(tx, e.ProductId)
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to update review")
+	}
+
+	return e, nil
+}
+
+// FindDeliveredOrderItem finds an item of the product in one of the user's
+// delivered shipments, used to make sure only buyers can review a product
+func (r reviewRepository) FindDeliveredOrderItem(uId uint, productId uint) (*domain.OrderItem, error) {
+	var item *domain.OrderItem
+	err := r.db.
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Joins("JOIN shipments ON shipments.order_id = order_items.order_id AND shipments.seller_id = order_items.seller_id").
+		Where("orders.user_id=? AND shipments.status=? AND order_items.product_id=?", uId, domain.OrderDelivered, productId).
+		First(&item).Error
+
+	if err != nil {
+		return nil, errors.New("no delivered order found for this product")
+	}
+
+	return item, nil
+}
+
+// refreshProductRating stores the average rating and review count of the
+// visible reviews on the product
+func refreshProductRating(tx *gorm.DB, productId uint) error {
+	var stats struct {
+		Average float64
+		Count   int64
+	}
+
+	err := tx.Model(&domain.Review{}).
+		Select("coalesce(avg(rating), 0) as average, count(*) as count").
+		Where("product_id=? AND hidden=false", productId).
+		Scan(&stats).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&domain.Product{}).Where("id=?", productId).UpdateColumns(map[string]interface{}{
+		"rating":       stats.Average,
+		"review_count": stats.Count,
+	}).Error
+}
+
+// isUniqueViolation tells if err comes from a unique index rejecting a row
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SellerRepository interface {
+	SaveSellerProfile(e *domain.SellerProfile) error
+	FindSellerProfile(sellerId uint) (*domain.SellerProfile, error)
+	FindSellerRating(sellerId uint) (float64, int64, error)
+
+	CreateSellerApplication(e *domain.SellerApplication) error
+	FindSellerApplicationById(id uint) (*domain.SellerApplication, error)
+	FindUserSellerApplication(uId uint) (*domain.SellerApplication, error)
+	FindSellerApplications(status string, limit int, offset int) ([]*domain.SellerApplication, int64, error)
+	ApproveSellerApplication(id uint, adminId uint, now time.Time) (*domain.SellerApplication, error)
+	RejectSellerApplication(id uint, adminId uint, reason string, now time.Time) (*domain.SellerApplication, error)
+}
+
+type sellerRepository struct {
+	db *gorm.DB
+}
+
+func NewSellerRepository(db *gorm.DB) SellerRepository {
+	return &sellerRepository{
+		db: db,
+	}
+}
+
+func (r sellerRepository) SaveSellerProfile(e *domain.SellerProfile) error {
+	err := r.db.Save(e).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to save seller profile")
+	}
+
+	return nil
+}
+
+func (r sellerRepository) FindSellerProfile(sellerId uint) (*domain.SellerProfile, error) {
+	var profile domain.SellerProfile
+	err := r.db.First(&profile, "user_id=?", sellerId).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("seller profile does not exist")
+	}
+
+	return &profile, nil
+}
+
+// FindSellerRating returns the average rating and count of the visible
+// reviews on all products of the seller
+func (r sellerRepository) FindSellerRating(sellerId uint) (float64, int64, error) {
+	var stats struct {
+		Average float64
+		Count   int64
+	}
+
+	err := r.db.Model(&domain.Review{}).
+		Select("coalesce(avg(reviews.rating), 0) as average, count(*) as count").
+		Joins("JOIN products ON products.id = reviews.product_id").
+		Where("products.user_id=? AND reviews.hidden=false", sellerId).
+		Scan(&stats).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return 0, 0, errors.New("failed to find seller rating")
+	}
+
+	return stats.Average, stats.Count, nil
+}
+
+func (r sellerRepository) CreateSellerApplication(e *domain.SellerApplication) error {
+	err := r.db.Create(e).Error
+	if isUniqueViolation(err) {
+		return errors.New("your application is already waiting for review")
+	}
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to create seller application")
+	}
+
+	return nil
+}
+
+func (r sellerRepository) FindSellerApplicationById(id uint) (*domain.SellerApplication, error) {
+	var application domain.SellerApplication
+	err := r.db.Preload("Documents").First(&application, id).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("seller application does not exist")
+	}
+
+	return &application, nil
+}
+
+// FindUserSellerApplication returns the latest application of the user
+func (r sellerRepository) FindUserSellerApplication(uId uint) (*domain.SellerApplication, error) {
+	var application domain.SellerApplication
+	err := r.db.Preload("Documents").Where("user_id=?", uId).Order("id desc").First(&application).Error
+	if err != nil {
+		return nil, errors.New("seller application does not exist")
+	}
+
+	return &application, nil
+}
+
+func (r sellerRepository) FindSellerApplications(status string, limit int, offset int) ([]*domain.SellerApplication, int64, error) {
+	var applications []*domain.SellerApplication
+	var total int64
+
+	query := r.db.Model(&domain.SellerApplication{})
+	if len(status) > 0 {
+		query = query.Where("status=?", status)
+	}
+	query = query.Session(&gorm.Session{})
+
+	err := query.Count(&total).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, 0, errors.New("failed to find seller applications")
+	}
+
+	// oldest first, in the order they are reviewed
+	err = query.Preload("Documents").Order("id").Limit(limit).Offset(offset).Find(&applications).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, 0, errors.New("failed to find seller applications")
+	}
+
+	return applications, total, nil
+}
+
+// ApproveSellerApplication makes the applicant a seller with the details of
+// the application and lets them know
+func (r sellerRepository) ApproveSellerApplication(id uint, adminId uint, now time.Time) (*domain.SellerApplication, error) {
+	var application domain.SellerApplication
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := reviewApplication(tx, &application, id, adminId, now)
+		if err != nil {
+			return err
+		}
+		application.Status = domain.ApplicationApproved
+
+		err = tx.Model(&domain.SellerApplication{}).Where("id=?", id).Updates(map[string]interface{}{
+			"status":      application.Status,
+			"reviewed_by": adminId,
+			"reviewed_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&domain.User{}).Where("id=?", application.UserId).Updates(domain.User{
+			FirstName: application.FirstName,
+			LastName:  application.LastName,
+			UserType:  domain.SELLER,
+			Currency:  application.Currency,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&domain.BankAccount{
+			UserId:      application.UserId,
+			BankAccount: application.BankAccount,
+			SwiftCode:   application.SwiftCode,
+			PaymentType: application.PaymentType,
+		}).Error
+		if err != nil {
+			log.Println("db_err:", err)
+			return errors.New("bank account of the application is already registered")
+		}
+
+		// the storefront starts out under the business name
+		err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&domain.SellerProfile{
+			UserId:      application.UserId,
+			DisplayName: application.BusinessName,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return queueNotification(tx, application.UserId, "Your seller application has been approved, welcome to the seller program")
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, err
+	}
+
+	return &application, nil
+}
+
+func (r sellerRepository) RejectSellerApplication(id uint, adminId uint, reason string, now time.Time) (*domain.SellerApplication, error) {
+	var application domain.SellerApplication
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := reviewApplication(tx, &application, id, adminId, now)
+		if err != nil {
+			return err
+		}
+		application.Status = domain.ApplicationRejected
+		application.RejectionReason = reason
+
+		err = tx.Model(&domain.SellerApplication{}).Where("id=?", id).Updates(map[string]interface{}{
+			"status":           application.Status,
+			"rejection_reason": reason,
+			"reviewed_by":      adminId,
+			"reviewed_at":      now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return queueNotification(tx, application.UserId, fmt.Sprintf("Your seller application has been rejected: %v", reason))
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, err
+	}
+
+	return &application, nil
+}
+
+// reviewApplication locks a pending application so it is only reviewed once
+func reviewApplication(tx *gorm.DB, application *domain.SellerApplication, id uint, adminId uint, now time.Time) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Documents").First(application, id).Error
+	if err != nil {
+		return errors.New("seller application does not exist")
+	}
+
+	if application.Status != domain.ApplicationPending {
+		return fmt.Errorf("seller application is already %v", application.Status)
+	}
+
+	application.ReviewedBy = adminId
+	application.ReviewedAt = &now
+	return nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+type ShippingRepository interface {
+	CreateShippingProfile(e *domain.ShippingProfile) error
+	FindSellerShippingProfiles(sellerId uint) ([]*domain.ShippingProfile, error)
+	FindShippingProfilesBySellers(sellerIds []uint) ([]*domain.ShippingProfile, error)
+	FindShippingProfileById(id uint) (*domain.ShippingProfile, error)
+	EditShippingProfile(e *domain.ShippingProfile) (*domain.ShippingProfile, error)
+	DeleteShippingProfile(id uint) error
+}
+
+type shippingRepository struct {
+	db *gorm.DB
+}
+
+func NewShippingRepository(db *gorm.DB) ShippingRepository {
+	return &shippingRepository{
+		db: db,
+	}
+}
+
+func (r shippingRepository) CreateShippingProfile(e *domain.ShippingProfile) error {
+	err := r.db.Create(e).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("create shipping profile failed")
+	}
+
+	return nil
+}
+
+func (r shippingRepository) FindSellerShippingProfiles(sellerId uint) ([]*domain.ShippingProfile, error) {
+	var profiles []*domain.ShippingProfile
+	err := r.db.Where("user_id=?", sellerId).Order("id").Find(&profiles).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find shipping profiles")
+	}
+
+	return profiles, nil
+}
+
+// FindShippingProfilesBySellers returns the active profiles of the sellers
+func (r shippingRepository) FindShippingProfilesBySellers(sellerIds []uint) ([]*domain.ShippingProfile, error) {
+	var profiles []*domain.ShippingProfile
+	err := r.db.Where("user_id IN ? AND active=?", sellerIds, true).Order("id").Find(&profiles).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find shipping profiles")
+	}
+
+	return profiles, nil
+}
+
+func (r shippingRepository) FindShippingProfileById(id uint) (*domain.ShippingProfile, error) {
+	var profile *domain.ShippingProfile
+	err := r.db.First(&profile, id).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("shipping profile does not exist")
+	}
+
+	return profile, nil
+}
+
+func (r shippingRepository) EditShippingProfile(e *domain.ShippingProfile) (*domain.ShippingProfile, error) {
+	err := r.db.Save(e).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("updating shipping profile failed")
+	}
+
+	return e, nil
+}
+
+func (r shippingRepository) DeleteShippingProfile(id uint) error {
+	err := r.db.Delete(&domain.ShippingProfile{}, id).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("error deleting shipping profile")
+	}
+
+	return nil
+}
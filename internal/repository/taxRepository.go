@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+type TaxRepository interface {
+	CreateTaxRule(e *domain.TaxRule) error
+	FindTaxRules() ([]*domain.TaxRule, error)
+	FindTaxRuleById(id uint) (*domain.TaxRule, error)
+	FindCountryTaxRules(country string) ([]*domain.TaxRule, error)
+	EditTaxRule(e *domain.TaxRule) (*domain.TaxRule, error)
+	DeleteTaxRule(id uint) error
+}
+
+type taxRepository struct {
+	db *gorm.DB
+}
+
+func NewTaxRepository(db *gorm.DB) TaxRepository {
+	return &taxRepository{
+		db: db,
+	}
+}
+
+func (r taxRepository) CreateTaxRule(e *domain.TaxRule) error {
+	err := r.db.Create(e).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("create tax rule failed")
+	}
+
+	return nil
+}
+
+func (r taxRepository) FindTaxRules() ([]*domain.TaxRule, error) {
+	var rules []*domain.TaxRule
+	err := r.db.Order("country, region, tax_class, id").Find(&rules).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find tax rules")
+	}
+
+	return rules, nil
+}
+
+func (r taxRepository) FindTaxRuleById(id uint) (*domain.TaxRule, error) {
+	var rule *domain.TaxRule
+	err := r.db.First(&rule, id).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("tax rule does not exist")
+	}
+
+	return rule, nil
+}
+
+// FindCountryTaxRules returns the active rules of a country, for all regions
+// and tax classes
+func (r taxRepository) FindCountryTaxRules(country string) ([]*domain.TaxRule, error) {
+	var rules []*domain.TaxRule
+	err := r.db.Where("country=? AND active=?", country, true).Order("id").Find(&rules).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find tax rules")
+	}
+
+	return rules, nil
+}
+
+func (r taxRepository) EditTaxRule(e *domain.TaxRule) (*domain.TaxRule, error) {
+	err := r.db.Save(e).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("updating tax rule failed")
+	}
+
+	return e, nil
+}
+
+func (r taxRepository) DeleteTaxRule(id uint) error {
+	err := r.db.Delete(&domain.TaxRule{}, id).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("error deleting tax rule")
+	}
+
+	return nil
+}
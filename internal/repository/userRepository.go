@@ -2,8 +2,10 @@ package repository
 
 import (
 	"errors"
+	"fmt"
 	"go-ecommerce-app/internal/domain"
 	"log"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -16,6 +18,32 @@ type UserRepository interface {
 	UpdateUser(id uint, u domain.User) (domain.User, error)
 
 	CreateBankAccount(e domain.BankAccount) error
+
+	CreateAddress(e *domain.Address) error
+	FindAddresses(uId uint) ([]*domain.Address, error)
+	FindAddressById(id uint, uId uint) (*domain.Address, error)
+	DeleteAddress(id uint, uId uint) error
+
+	FindCartItems(uId uint) ([]*domain.Cart, error)
+	FindCartItem(uId uint, pId uint, vId uint) (domain.Cart, error)
+	CreateCart(c domain.Cart) error
+	UpdateCart(c domain.Cart) error
+	DeleteCartById(id uint) error
+	FindGuestCartItems(guestId string) ([]*domain.Cart, error)
+	FindGuestCartItem(guestId string, pId uint, vId uint) (domain.Cart, error)
+	DeleteGuestCart(guestId string) error
+	DeleteStaleGuestCarts(before time.Time) (int64, error)
+
+	CreateOrder(o *domain.Order, redemption *domain.CouponRedemption) error
+	ConfirmOrderPayment(id uint, transactionId string, paidAt time.Time) error
+	FindOrders(uId uint) ([]*domain.Order, error)
+	FindOrderById(id uint, uId uint) (*domain.Order, error)
+	FindSellerOrders(sellerId uint) ([]*domain.Order, error)
+	FindSellerOrderById(id uint, sellerId uint) (*domain.Order, error)
+	UpdateShipmentStatus(id uint, status string, trackingNumber string, now time.Time) error
+	CancelOrder(id uint, uId uint, now time.Time) (*domain.Order, error)
+	ClaimOrderRefund(id uint) error
+	SetOrderRefund(id uint, refundId string) error
 }
 
 type userRepository struct {
@@ -75,3 +103,462 @@ func (r userRepository) UpdateUser(id uint, u domain.User) (domain.User, error)
 func (r userRepository) CreateBankAccount(e domain.BankAccount) error {
 	return r.db.Create(&e).Error
 }
+
+func (r userRepository) CreateAddress(e *domain.Address) error {
+	err := r.db.Create(e).Error
+	if err != nil {
+		log.Println("create address error: ", err)
+		return errors.New("failed to create address")
+	}
+	return nil
+}
+
+func (r userRepository) FindAddresses(uId uint) ([]*domain.Address, error) {
+	var addresses []*domain.Address
+	err := r.db.Where("user_id=?", uId).Order("id").Find(&addresses).Error
+	if err != nil {
+		log.Println("find addresses error: ", err)
+		return nil, errors.New("failed to find addresses")
+	}
+	return addresses, nil
+}
+
+func (r userRepository) FindAddressById(id uint, uId uint) (*domain.Address, error) {
+	var address *domain.Address
+	err := r.db.Where("id=? AND user_id=?", id, uId).First(&address).Error
+	if err != nil {
+		log.Println("find address error: ", err)
+		return nil, errors.New("address does not exist")
+	}
+	return address, nil
+}
+
+func (r userRepository) DeleteAddress(id uint, uId uint) error {
+	return r.db.Where("id=? AND user_id=?", id, uId).Delete(&domain.Address{}).Error
+}
+
+func (r userRepository) FindCartItems(uId uint) ([]*domain.Cart, error) {
+	var carts []*domain.Cart
+	err := r.db.Where("user_id=?", uId).Order("id").Find(&carts).Error
+	if err != nil {
+		log.Println("find cart error: ", err)
+		return nil, errors.New("failed to find cart")
+	}
+
+	return carts, nil
+}
+
+func (r userRepository) FindCartItem(uId uint, pId uint, vId uint) (domain.Cart, error) {
+	cartItem := domain.Cart{}
+	err := r.db.Where("user_id=? AND product_id=? AND variant_id=?", uId, pId, vId).First(&cartItem).Error
+	return cartItem, err
+}
+
+func (r userRepository) CreateCart(c domain.Cart) error {
+	return r.db.Create(&c).Error
+}
+
+func (r userRepository) UpdateCart(c domain.Cart) error {
+	var cart domain.Cart
+	return r.db.Model(&cart).Clauses(clause.Returning{}).Where("id=?", c.ID).Updates(c).Error
+}
+
+func (r userRepository) DeleteCartById(id uint) error {
+	return r.db.Delete(&domain.Cart{}, id).Error
+}
+
+func (r userRepository) FindGuestCartItems(guestId string) ([]*domain.Cart, error) {
+	var carts []*domain.Cart
+	err := r.db.Where("user_id=0 AND guest_id=?", guestId).Order("id").Find(&carts).Error
+	if err != nil {
+		log.Println("find cart error: ", err)
+		return nil, errors.New("failed to find cart")
+	}
+
+	return carts, nil
+}
+
+func (r userRepository) FindGuestCartItem(guestId string, pId uint, vId uint) (domain.Cart, error) {
+	cartItem := domain.Cart{}
+	err := r.db.Where("user_id=0 AND guest_id=? AND product_id=? AND variant_id=?", guestId, pId, vId).First(&cartItem).Error
+	return cartItem, err
+}
+
+func (r userRepository) DeleteGuestCart(guestId string) error {
+	return r.db.Where("user_id=0 AND guest_id=?", guestId).Delete(&domain.Cart{}).Error
+}
+
+// DeleteStaleGuestCarts removes guest carts nobody touched since before
+func (r userRepository) DeleteStaleGuestCarts(before time.Time) (int64, error) {
+	result := r.db.Where("user_id=0 AND guest_id IN (?)", r.db.Model(&domain.Cart{}).
+		Select("guest_id").
+		Where("user_id=0").
+		Group("guest_id").
+		Having("MAX(updated_at)<?", before)).
+		Delete(&domain.Cart{})
+	if result.Error != nil {
+		log.Println("db_err:", result.Error)
+		return 0, errors.New("failed to delete guest carts")
+	}
+
+	return result.RowsAffected, nil
+}
+
+// CreateOrder saves the pending order, reserves stock for its items until
+// the order expires, redeems the coupon, if any, and empties the user's cart
+// in a single transaction
+func (r userRepository) CreateOrder(o *domain.Order, redemption *domain.CouponRedemption) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(o).Error; err != nil {
+			log.Println("create order error: ", err)
+			return errors.New("failed to create order")
+		}
+
+		for _, item := range o.Items {
+			err := reserveStock(tx, &domain.StockReservation{
+				OrderId:   o.ID,
+				UserId:    o.UserId,
+				ProductId: item.ProductId,
+				VariantId: item.VariantId,
+				Qty:       item.Qty,
+				Status:    domain.ReservationActive,
+				ExpiresAt: o.ExpiresAt,
+			})
+			if errors.Is(err, ErrOutOfStock) {
+				return fmt.Errorf("%v is out of stock", item.Name)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if redemption != nil {
+			redemption.OrderId = o.ID
+			redemption.UserId = o.UserId
+			if err := redeemCoupon(tx, redemption); err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("user_id=?", o.UserId).Delete(&domain.Cart{}).Error
+	})
+
+	return err
+}
+
+// ConfirmOrderPayment marks a pending order paid, takes its reserved stock
+// out of inventory, credits the sellers and issues their invoices
+func (r userRepository) ConfirmOrderPayment(id uint, transactionId string, paidAt time.Time) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Order{}).
+			Where("id=? AND status=?", id, domain.OrderPending).
+			Updates(map[string]interface{}{
+				"status":         domain.OrderPaid,
+				"transaction_id": transactionId,
+				"paid_at":        paidAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("order is not waiting for payment")
+		}
+
+		err := tx.Model(&domain.Shipment{}).Where("order_id=?", id).Update("status", domain.OrderPaid).Error
+		if err != nil {
+			return err
+		}
+
+		err = creditShipments(tx, id)
+		if err != nil {
+			return err
+		}
+
+		err = issueInvoices(tx, id, paidAt)
+		if err != nil {
+			return err
+		}
+
+		err = markCartRecovered(tx, id, paidAt)
+		if err != nil {
+			return err
+		}
+
+		return convertReservations(tx, id, paidAt)
+	})
+
+	if err != nil {
+		log.Println("confirm payment error: ", err)
+		return err
+	}
+
+	return nil
+}
+
+func (r userRepository) FindOrders(uId uint) ([]*domain.Order, error) {
+	var orders []*domain.Order
+	err := r.db.Preload("Items.Product", withDeleted).Preload("Items.Taxes").Preload("Shipments").Where("user_id=?", uId).Order("id desc").Find(&orders).Error
+	if err != nil {
+		log.Println("find orders error: ", err)
+		return nil, errors.New("failed to find orders")
+	}
+
+	return orders, nil
+}
+
+func (r userRepository) FindOrderById(id uint, uId uint) (*domain.Order, error) {
+	var order *domain.Order
+	err := r.db.Preload("Items.Product", withDeleted).Preload("Items.Taxes").Preload("Shipments").Where("id=? AND user_id=?", id, uId).First(&order).Error
+	if err != nil {
+		log.Println("find order error: ", err)
+		return nil, errors.New("order does not exist")
+	}
+
+	return order, nil
+}
+
+// FindSellerOrders returns the orders containing at least one item of the
+// seller, with only the seller's items and shipment
+func (r userRepository) FindSellerOrders(sellerId uint) ([]*domain.Order, error) {
+	var orders []*domain.Order
+	err := r.sellerOrders(sellerId).
+		Order("id desc").
+		Find(&orders).Error
+	if err != nil {
+		log.Println("find orders error: ", err)
+		return nil, errors.New("failed to find orders")
+	}
+
+	return orders, nil
+}
+
+func (r userRepository) FindSellerOrderById(id uint, sellerId uint) (*domain.Order, error) {
+	var order *domain.Order
+	err := r.sellerOrders(sellerId).Where("id=?", id).First(&order).Error
+	if err != nil {
+		log.Println("find order error: ", err)
+		return nil, errors.New("order does not exist")
+	}
+
+	return order, nil
+}
+
+func (r userRepository) sellerOrders(sellerId uint) *gorm.DB {
+	return r.db.
+		Preload("Items", "seller_id=?", sellerId).
+		Preload("Items.Product", withDeleted).
+		Preload("Items.Taxes").
+		Preload("Shipments", "seller_id=?", sellerId).
+		Where("id IN (?)", r.db.Model(&domain.Shipment{}).Select("order_id").Where("seller_id=?", sellerId))
+}
+
+// UpdateShipmentStatus moves the shipment on and brings the status of its
+// order in line with all of the order's shipments
+func (r userRepository) UpdateShipmentStatus(id uint, status string, trackingNumber string, now time.Time) error {
+	// a transition that is not allowed is reported as is
+	var moveErr error
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var shipment domain.Shipment
+		if err := tx.First(&shipment, id).Error; err != nil {
+			return err
+		}
+
+		// the buyer may be cancelling the order right now, lock it as
+		// CancelOrder does before the shipment
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&domain.Order{}, shipment.OrderId).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&shipment, id).Error
+		if err != nil {
+			return err
+		}
+
+		if !shipment.CanMoveTo(status) {
+			moveErr = fmt.Errorf("shipment can not be moved from %v to %v", shipment.Status, status)
+			return moveErr
+		}
+
+		updates := map[string]interface{}{"status": status}
+		switch status {
+		case domain.OrderShipped:
+			updates["shipped_at"] = now
+			if len(trackingNumber) > 0 {
+				updates["tracking_number"] = trackingNumber
+			}
+		case domain.OrderDelivered:
+			updates["delivered_at"] = now
+		}
+
+		result := tx.Model(&domain.Shipment{}).Where("id=? AND status=?", id, shipment.Status).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			moveErr = errors.New("shipment was updated in the meantime, please try again")
+			return moveErr
+		}
+
+		var shipments []domain.Shipment
+		err = tx.Where("order_id=?", shipment.OrderId).Find(&shipments).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&domain.Order{}).Where("id=?", shipment.OrderId).
+			Update("status", domain.StatusOf(shipments)).Error
+	})
+
+	if moveErr != nil {
+		return moveErr
+	}
+	if err != nil {
+		log.Println("update shipment error: ", err)
+		return errors.New("failed to update order")
+	}
+
+	return nil
+}
+
+// CancelOrder cancels the order and its shipments if none of them has been
+// sent yet. Reserved stock is released, sold stock is put back, the coupon
+// can be used again, the sellers' balances give back what was paid and the
+// sellers are told. It returns the order as it was before cancelling.
+func (r userRepository) CancelOrder(id uint, uId uint, now time.Time) (*domain.Order, error) {
+	var order domain.Order
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Items").Preload("Shipments").
+			Where("id=? AND user_id=?", id, uId).
+			First(&order).Error
+		if err != nil {
+			return errors.New("order does not exist")
+		}
+
+		if !order.Cancellable() {
+			return fmt.Errorf("order is %v, it can no longer be cancelled", order.Status)
+		}
+
+		// a seller may be sending a shipment right now
+		result := tx.Model(&domain.Shipment{}).
+			Where("order_id=? AND status IN ?", id, []string{domain.OrderPending, domain.OrderPaid}).
+			Update("status", domain.OrderCancelled)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(order.Shipments)) {
+			return errors.New("order has been shipped, it can no longer be cancelled")
+		}
+
+		if order.Status == domain.OrderPending {
+			err = tx.Model(&domain.StockReservation{}).
+				Where("order_id=? AND status=?", id, domain.ReservationActive).
+				Update("status", domain.ReservationReleased).Error
+			if err != nil {
+				return err
+			}
+		} else {
+			for _, item := range order.Items {
+				err := adjustStock(tx, &domain.InventoryMovement{
+					ProductId: item.ProductId,
+					VariantId: item.VariantId,
+					UserId:    uId,
+					OrderId:   id,
+					Change:    int(item.Qty),
+					Reason:    domain.MovementCancel,
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			for _, s := range order.Shipments {
+				err := tx.Create(&domain.BalanceEntry{
+					SellerId:   s.SellerId,
+					Type:       domain.BalanceRefund,
+					OrderId:    id,
+					ShipmentId: s.ID,
+					Amount:     s.PayoutOf(domain.NewMoney(-s.Amount.Amount, s.Amount.Currency)),
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+
+			err = creditInvoices(tx, id, now)
+			if err != nil {
+				return err
+			}
+
+			err = unmarkCartRecovered(tx, id)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Where("order_id=?", id).Delete(&domain.CouponRedemption{}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&domain.Order{}).Where("id=?", id).Updates(map[string]interface{}{
+			"status":       domain.OrderCancelled,
+			"cancelled_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		for _, s := range order.Shipments {
+			msg := fmt.Sprintf("Order %v was cancelled by the buyer, please do not ship it", id)
+			if err := queueNotification(tx, s.SellerId, msg); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Println("cancel order error: ", err)
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// ClaimOrderRefund marks the refund of a cancelled order pending before the
+// gateway is called, so concurrent cancels can not both start a refund
+func (r userRepository) ClaimOrderRefund(id uint) error {
+	result := r.db.Model(&domain.Order{}).Where("id=? AND refund_id=''", id).Update("refund_id", domain.RefundPending)
+	if result.Error != nil {
+		log.Println("update order error: ", result.Error)
+		return errors.New("failed to update order")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("order is already being refunded")
+	}
+
+	return nil
+}
+
+// SetOrderRefund records the refund of a claimed order refund
+func (r userRepository) SetOrderRefund(id uint, refundId string) error {
+	err := r.db.Model(&domain.Order{}).
+		Where("id=? AND refund_id=?", id, domain.RefundPending).
+		Update("refund_id", refundId).Error
+	if err != nil {
+		log.Println("update order error: ", err)
+		return errors.New("failed to update order")
+	}
+
+	return nil
+}
+
+// withDeleted lets order items show products that were deleted since
+func withDeleted(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
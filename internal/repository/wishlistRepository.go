@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"errors"
+	"go-ecommerce-app/internal/domain"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WishlistRepository interface {
+	SaveWishlistItem(e *domain.WishlistItem, updateNotify bool) error
+	FindWishlist(uId uint) ([]*domain.WishlistItem, error)
+	FindWishlistItemById(id uint, uId uint) (*domain.WishlistItem, error)
+	DeleteWishlistItem(id uint) error
+	FindWatchedWishlistItems() ([]*domain.WishlistItem, error)
+	UpdateWishlistWatch(e *domain.WishlistItem, messages []string) error
+}
+
+type wishlistRepository struct {
+	db *gorm.DB
+}
+
+func NewWishlistRepository(db *gorm.DB) WishlistRepository {
+	return &wishlistRepository{
+		db: db,
+	}
+}
+
+// SaveWishlistItem adds the product to the wishlist. When it is already there
+// the notification choices are only changed with updateNotify.
+func (r wishlistRepository) SaveWishlistItem(e *domain.WishlistItem, updateNotify bool) error {
+	columns := []string{"updated_at"}
+	if updateNotify {
+		columns = append(columns, "notify_price_drop", "notify_in_stock")
+	}
+
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "product_id"}, {Name: "variant_id"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(e).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to save wishlist item")
+	}
+
+	return nil
+}
+
+func (r wishlistRepository) FindWishlist(uId uint) ([]*domain.WishlistItem, error) {
+	var items []*domain.WishlistItem
+	err := r.db.Where("user_id=?", uId).Order("id desc").Find(&items).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find wishlist")
+	}
+
+	return items, nil
+}
+
+func (r wishlistRepository) FindWishlistItemById(id uint, uId uint) (*domain.WishlistItem, error) {
+	var item *domain.WishlistItem
+	err := r.db.Where("id=? AND user_id=?", id, uId).First(&item).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("wishlist item does not exist")
+	}
+
+	return item, nil
+}
+
+func (r wishlistRepository) DeleteWishlistItem(id uint) error {
+	err := r.db.Delete(&domain.WishlistItem{}, id).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to remove wishlist item")
+	}
+
+	return nil
+}
+
+// FindWatchedWishlistItems returns the items whose buyers asked to be told
+// about price drops or restocks, with their products. Items of deleted
+// products are left out.
+func (r wishlistRepository) FindWatchedWishlistItems() ([]*domain.WishlistItem, error) {
+	var items []*domain.WishlistItem
+	err := r.db.
+		Joins("JOIN products ON products.id = wishlist_items.product_id AND products.deleted_at IS NULL").
+		Where("wishlist_items.notify_price_drop OR wishlist_items.notify_in_stock").
+		Order("wishlist_items.product_id").
+		Find(&items).Error
+	if err != nil {
+		log.Println("db_err:", err)
+		return nil, errors.New("failed to find wishlist items")
+	}
+
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ProductId)
+	}
+
+	var products []*domain.Product
+	if len(ids) > 0 {
+		err = r.db.Scopes(withProductDetails).Where("id IN ?", ids).Find(&products).Error
+		if err == nil {
+			err = applyComputedFields(r.db, products...)
+		}
+		if err != nil {
+			log.Println("db_err:", err)
+			return nil, errors.New("failed to find wishlist items")
+		}
+	}
+
+	byId := make(map[uint]*domain.Product, len(products))
+	for _, p := range products {
+		byId[p.ID] = p
+	}
+	for _, item := range items {
+		item.Product = byId[item.ProductId]
+	}
+
+	return items, nil
+}
+
+// UpdateWishlistWatch stores the price and availability just seen and
+// queues the messages for the buyer
+func (r wishlistRepository) UpdateWishlistWatch(e *domain.WishlistItem, messages []string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.WishlistItem{}).Where("id=?", e.ID).Updates(map[string]interface{}{
+			"last_price_amount":   e.LastPrice.Amount,
+			"last_price_currency": e.LastPrice.Currency,
+			"was_available":       e.WasAvailable,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range messages {
+			if err := queueNotification(tx, e.UserId, msg); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Println("db_err:", err)
+		return errors.New("failed to update wishlist item")
+	}
+
+	return nil
+}
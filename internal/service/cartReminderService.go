@@ -0,0 +1,103 @@
+package service
+
+import (
+	"fmt"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"time"
+)
+
+// carts left longer than this are not reminded about any more
+const abandonedCartLookback = 7 * 24 * time.Hour
+
+type CartReminderService struct {
+	Repo     repository.CartReminderRepository
+	UserRepo repository.UserRepository
+	Auth     helper.Auth
+	Config   config.AppConfig
+}
+
+// SendReminders reminds buyers of the carts they left, once per cart until
+// it is changed again
+func (s CartReminderService) SendReminders(now time.Time) (int, error) {
+	carts, err := s.Repo.FindAbandonedCarts(now.Add(-s.Config.AbandonedCartAfter), now.Add(-abandonedCartLookback), 100)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, cart := range carts {
+		items, err := s.UserRepo.FindCartItems(cart.UserId)
+		if err != nil {
+			return sent, err
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		reminder := domain.CartReminder{
+			UserId:        cart.UserId,
+			CartUpdatedAt: cart.LastUpdated,
+			SentAt:        now,
+		}
+		for _, item := range items {
+			reminder.Items = append(reminder.Items, domain.CartReminderItem{
+				ProductId: item.ProductId,
+				SellerId:  item.SellerId,
+				Qty:       item.Qty,
+				Price:     item.Price,
+			})
+		}
+
+		message := fmt.Sprintf("You left %v in your cart", items[0].Name)
+		if len(items) > 1 {
+			message = fmt.Sprintf("You left %v and %v more in your cart", items[0].Name, len(items)-1)
+		}
+
+		err = s.Repo.CreateReminder(&reminder, message)
+		if err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+func (s CartReminderService) SetCartReminders(input dto.CartRemindersRequest, u domain.User) error {
+	return s.Repo.SetCartReminders(u.ID, input.Enabled)
+}
+
+// GetRecoveryReport reports on the reminders of the last days, only those
+// about the seller's products when sellerId is not 0
+func (s CartReminderService) GetRecoveryReport(days int, sellerId uint) (*dto.CartRecoveryReport, error) {
+	if days <= 0 {
+		days = 30
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	sent, recovered, err := s.Repo.CountReminders(sellerId, since)
+	if err != nil {
+		return nil, err
+	}
+
+	revenue, err := s.Repo.SumRecoveredRevenue(sellerId, since)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dto.CartRecoveryReport{
+		Since:            since,
+		Sent:             sent,
+		Recovered:        recovered,
+		RecoveredRevenue: revenue,
+	}
+	if sent > 0 {
+		report.RecoveryRate = float64(recovered) * 100 / float64(sent)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const maxImportRows = 5000
+
+var productCsvColumns = []string{"sku", "name", "description", "category_id", "price", "currency", "stock", "image_url"}
+
+// ImportProducts creates or updates the seller's products from a csv file,
+// matching existing products by sku. Nothing is written if any row is
+// invalid or when dryRun is set.
+func (s CatalogService) ImportProducts(r io.Reader, dryRun bool, user domain.User) (*dto.ProductImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("csv file is empty or not valid")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(productCsvColumns, name) {
+			return nil, fmt.Errorf("unknown column %v", name)
+		}
+		columns[name] = i
+	}
+
+	for _, required := range []string{"sku", "name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("column %v is required", required)
+		}
+	}
+
+	existing, _, err := s.Repo.FindSellerProducts(int(user.ID), false, 0, 0)
+	if err != nil {
+		return nil, errors.New("unable to load seller products")
+	}
+	bySku := make(map[string]*domain.Product)
+	for _, p := range existing {
+		if len(p.Sku) > 0 {
+			bySku[p.Sku] = p
+		}
+	}
+
+	// a deleted product still holds its sku until it is purged, importing
+	// the sku again restores it as a draft
+	deleted, err := s.Repo.FindDeletedSellerProducts(int(user.ID))
+	if err != nil {
+		return nil, errors.New("unable to load seller products")
+	}
+	for _, p := range deleted {
+		if _, ok := bySku[p.Sku]; !ok && len(p.Sku) > 0 {
+			bySku[p.Sku] = p
+		}
+	}
+
+	categories, err := s.Repo.FindCategories()
+	if err != nil {
+		return nil, errors.New("unable to load categories")
+	}
+	categoryIds := make(map[uint]bool)
+	for _, c := range categories {
+		categoryIds[c.ID] = true
+	}
+
+	rates, err := loadExchangeRates(s.Rates, s.Config.Currency)
+	if err != nil {
+		return nil, errors.New("unable to load currencies")
+	}
+
+	result := &dto.ProductImportResult{DryRun: dryRun}
+	var products []*domain.Product
+	seen := make(map[string]int)
+
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		result.Rows++
+		if result.Rows > maxImportRows {
+			return nil, fmt.Errorf("a single import can have at most %v rows", maxImportRows)
+		}
+
+		if err != nil {
+			result.Errors = append(result.Errors, dto.ProductImportRowError{
+				Row:    row,
+				Errors: []string{"row is not valid csv"},
+			})
+			continue
+		}
+
+		value := func(column string) (string, bool) {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return "", false
+			}
+			return strings.TrimSpace(record[i]), true
+		}
+
+		sku, _ := value("sku")
+		var rowErrors []string
+
+		if len(sku) == 0 {
+			rowErrors = append(rowErrors, "sku is required")
+		} else if first, ok := seen[sku]; ok {
+			rowErrors = append(rowErrors, fmt.Sprintf("sku is already used on row %v", first))
+		} else {
+			seen[sku] = row
+		}
+
+		product, isUpdate := bySku[sku]
+		if isUpdate {
+			// work on a copy so a failed import leaves nothing half applied
+			copied := *product
+			product = &copied
+			if product.DeletedAt.Valid {
+				product.DeletedAt.Valid = false
+				product.Status = domain.ProductDraft
+			}
+		} else {
+			product = &domain.Product{Sku: sku, UserId: int(user.ID), Status: domain.ProductDraft}
+		}
+
+		if name, _ := value("name"); len(name) > 0 {
+			product.Name = name
+		} else {
+			rowErrors = append(rowErrors, "name is required")
+		}
+
+		if description, ok := value("description"); ok {
+			product.Description = description
+		}
+
+		if imageUrl, ok := value("image_url"); ok {
+			product.ImageUrl = imageUrl
+		}
+
+		if raw, ok := value("category_id"); ok && len(raw) > 0 {
+			categoryId, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil || !categoryIds[uint(categoryId)] {
+				rowErrors = append(rowErrors, fmt.Sprintf("category %v does not exist", raw))
+			} else {
+				product.CategoryId = uint(categoryId)
+			}
+		}
+
+		// new products default to the store currency, existing ones keep theirs
+		currency := product.Price.Currency
+		if len(currency) == 0 {
+			currency = s.Config.Currency
+		}
+		if raw, _ := value("currency"); len(raw) > 0 {
+			raw = strings.ToUpper(raw)
+			if isUpdate && raw != currency {
+				rowErrors = append(rowErrors, fmt.Sprintf("product is priced in %v, its currency can not be changed", currency))
+			} else if !rates.Supports(raw) {
+				rowErrors = append(rowErrors, fmt.Sprintf("currency %v is not supported", raw))
+			}
+			currency = raw
+		}
+
+		raw, _ := value("price")
+		price, err := domain.ParseMoney(raw, currency)
+		if err != nil || !price.IsPositive() {
+			rowErrors = append(rowErrors, fmt.Sprintf("price must be a %v amount greater than 0", currency))
+		} else {
+			product.Price = price
+		}
+
+		if raw, ok := value("stock"); ok && len(raw) > 0 {
+			stock, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil {
+				rowErrors = append(rowErrors, "stock must be a whole number of 0 or more")
+			} else if len(product.Variants) > 0 && uint(stock) != product.Stock {
+				rowErrors = append(rowErrors, "stock of a product with variants is managed per variant")
+			} else {
+				product.Stock = uint(stock)
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, dto.ProductImportRowError{
+				Row:    row,
+				Sku:    sku,
+				Errors: rowErrors,
+			})
+			continue
+		}
+
+		if isUpdate {
+			result.Updated++
+		} else {
+			result.Created++
+		}
+		products = append(products, product)
+	}
+
+	if len(result.Errors) > 0 || dryRun {
+		return result, nil
+	}
+
+	err = s.Repo.UpsertProducts(products)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ExportProducts writes all products of the seller as csv in the import format
+func (s CatalogService) ExportProducts(w io.Writer, user domain.User) error {
+	products, _, err := s.Repo.FindSellerProducts(int(user.ID), false, 0, 0)
+	if err != nil {
+		return errors.New("unable to load seller products")
+	}
+
+	writer := csv.NewWriter(w)
+
+	err = writer.Write(productCsvColumns)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range products {
+		err = writer.Write([]string{
+			p.Sku,
+			p.Name,
+			p.Description,
+			strconv.FormatUint(uint64(p.CategoryId), 10),
+			p.Price.Decimal(),
+			p.Price.Currency,
+			strconv.FormatUint(uint64(p.Stock), 10),
+			p.ImageUrl,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -1,21 +1,35 @@
 package service
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"go-ecommerce-app/config"
 	"go-ecommerce-app/internal/domain"
 	"go-ecommerce-app/internal/dto"
 	"go-ecommerce-app/internal/helper"
 	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/storage"
+	"io"
 	"log"
+	"mime/multipart"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
 )
 
 type CatalogService struct {
-	Repo   repository.CatalogRepository
-	Auth   helper.Auth
-	Config config.AppConfig
+	Repo    repository.CatalogRepository
+	IRepo   repository.InventoryRepository
+	Rates   repository.ExchangeRateRepository
+	Auth    helper.Auth
+	Config  config.AppConfig
+	Storage storage.Storage
 }
 
+const maxProductImages = 10
+
 func (s CatalogService) CreateCategory(input dto.CreateCategoryRequest) error {
 	err := s.Repo.CreateCategory(&domain.Category{
 		Name:         input.Name,
@@ -66,6 +80,12 @@ func (s CatalogService) GetCategories() ([]*domain.Category, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	err = s.applyProductCounts(categories)
+	if err != nil {
+		return nil, err
+	}
+
 	return categories, err
 }
 
@@ -77,26 +97,119 @@ func (s CatalogService) GetCategory(id int) (*domain.Category, error) {
 	return category, nil
 }
 
-func (s CatalogService) GetProducts() ([]*domain.Product, error) {
+func (s CatalogService) GetCategoryWithProducts(id int, page dto.PaginationRequest, currency string) (*domain.Category, *dto.PaginationResponse, error) {
+	page.Normalize()
+
+	category, err := s.Repo.FindCategoryWithProducts(id, page.Limit, page.Offset())
+	if err != nil {
+		return nil, nil, errors.New("category does not exist")
+	}
+
+	categories, err := s.Repo.FindCategories()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = s.applyProductCounts(categories)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, c := range categories {
+		if c.ID == category.ID {
+			category.ProductCount = c.ProductCount
+			category.TotalProductCount = c.TotalProductCount
+		}
+	}
+
+	products := make([]*domain.Product, len(category.Products))
+	for i := range category.Products {
+		products[i] = &category.Products[i]
+	}
+
+	err = s.showPricesIn(currency, products...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return category, &dto.PaginationResponse{
+		Page:  page.Page,
+		Limit: page.Limit,
+		Total: category.ProductCount,
+	}, nil
+}
+
+// applyProductCounts sets the direct product count of every category and
+// the total including all of its descendant categories
+func (s CatalogService) applyProductCounts(categories []*domain.Category) error {
+	counts, err := s.Repo.CountProductsByCategory()
+	if err != nil {
+		return err
+	}
+
+	children := make(map[uint][]*domain.Category)
+	for _, c := range categories {
+		c.ProductCount = counts[c.ID]
+		if c.ParentId > 0 && c.ParentId != c.ID {
+			children[c.ParentId] = append(children[c.ParentId], c)
+		}
+	}
+
+	var total func(c *domain.Category, visited map[uint]bool) int64
+	total = func(c *domain.Category, visited map[uint]bool) int64 {
+		if visited[c.ID] {
+			return 0
+		}
+		visited[c.ID] = true
+
+		sum := c.ProductCount
+		for _, child := range children[c.ID] {
+			sum += total(child, visited)
+		}
+		return sum
+	}
+
+	for _, c := range categories {
+		c.TotalProductCount = total(c, map[uint]bool{})
+	}
+
+	return nil
+}
+
+func (s CatalogService) GetProducts(currency string) ([]*domain.Product, error) {
 	product, err := s.Repo.FindProducts()
 	if err != nil {
 		return nil, err
 	}
+
+	err = s.showPricesIn(currency, product...)
+	if err != nil {
+		return nil, err
+	}
 	return product, nil
 }
 
-func (s CatalogService) GetProduct(id int) (*domain.Product, error) {
+func (s CatalogService) GetProduct(id int, currency string) (*domain.Product, error) {
 	product, err := s.Repo.FindProductById(id)
 
-	if err != nil {
+	if err != nil || !product.IsVisible() {
 		return nil, errors.New("product does not exist")
 	}
 
+	err = s.showPricesIn(currency, product)
+	if err != nil {
+		return nil, err
+	}
+
 	return product, nil
 }
 
+func (s CatalogService) GetSellerProductById(id int, user domain.User) (*domain.Product, error) {
+	return s.findOwnedProduct(id, user)
+}
+
 func (s CatalogService) GetSellerProduct(id int) ([]*domain.Product, error) {
-	products, err := s.Repo.FindSellerProducts(id)
+	products, _, err := s.Repo.FindSellerProducts(id, false, 0, 0)
 
 	if err != nil {
 		return nil, errors.New("product does not exist")
@@ -106,15 +219,26 @@ func (s CatalogService) GetSellerProduct(id int) ([]*domain.Product, error) {
 }
 
 func (s CatalogService) CreateProduct(input dto.CreateProductRequest, user domain.User) error {
+	price, err := s.listingPrice(input.Price)
+	if err != nil {
+		return err
+	}
 
-	err := s.Repo.CreateProduct(&domain.Product{
+	err = s.Repo.CreateProduct(&domain.Product{
+		Sku:         strings.TrimSpace(input.Sku),
 		Name:        input.Name,
 		Description: input.Description,
 		CategoryId:  input.CategoryId,
-		Price:       input.Price,
+		Price:       price,
 		UserId:      int(user.ID),
 		Stock:       input.Stock,
 		ImageUrl:    input.ImageUrl,
+		TaxClass:    taxClassOrDefault(input.TaxClass),
+		Weight:      input.Weight,
+		Length:      input.Length,
+		Width:       input.Width,
+		Height:      input.Height,
+		Status:      domain.ProductDraft,
 	})
 
 	return err
@@ -129,12 +253,19 @@ func (s CatalogService) EditProduct(id int, input dto.CreateProductRequest, user
 		return nil, errors.New("you do not have manage rights of this product")
 	}
 
+	if len(strings.TrimSpace(input.Sku)) > 0 {
+		existingProduct.Sku = strings.TrimSpace(input.Sku)
+	}
+
 	if len(input.Name) > 0 {
 		existingProduct.Name = input.Name
 	}
 
-	if input.Price > 0 {
-		existingProduct.Price = input.Price
+	if input.Price.IsPositive() {
+		existingProduct.Price, err = priceIn(input.Price, existingProduct.Price.Currency)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if len(input.Description) > 0 {
@@ -145,6 +276,21 @@ func (s CatalogService) EditProduct(id int, input dto.CreateProductRequest, user
 		existingProduct.CategoryId = input.CategoryId
 	}
 
+	if len(input.TaxClass) > 0 {
+		existingProduct.TaxClass = taxClassOrDefault(input.TaxClass)
+	}
+
+	if input.Weight > 0 {
+		existingProduct.Weight = input.Weight
+	}
+
+	// dimensions are changed together
+	if input.Length > 0 && input.Width > 0 && input.Height > 0 {
+		existingProduct.Length = input.Length
+		existingProduct.Width = input.Width
+		existingProduct.Height = input.Height
+	}
+
 	updatedProduct, err := s.Repo.EditProduct(existingProduct)
 	if err != nil {
 		return nil, err
@@ -153,23 +299,131 @@ func (s CatalogService) EditProduct(id int, input dto.CreateProductRequest, user
 	return updatedProduct, nil
 }
 
-func (s CatalogService) UpdateProductStock(e *domain.Product) (*domain.Product, error) {
-	product, err := s.Repo.FindProductById(int(e.ID))
+func (s CatalogService) UpdateProductStock(id int, input dto.UpdateStockRequest, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
 	if err != nil {
-		return nil, errors.New("product does not exist")
+		return nil, err
 	}
 
-	if product.UserId != e.UserId {
-		return nil, errors.New("you do not have manage rights of this product")
+	if len(product.Variants) == 0 && input.VariantId > 0 {
+		return nil, errors.New("product does not have variants")
+	}
+
+	if len(product.Variants) > 0 {
+		if _, ok := product.FindVariant(input.VariantId); !ok {
+			return nil, errors.New("please provide a valid variant of this product")
+		}
 	}
 
-	product.Stock = e.Stock
-	updatedProduct, err := s.Repo.EditProduct(product)
+	movement := &domain.InventoryMovement{
+		ProductId: product.ID,
+		VariantId: input.VariantId,
+		UserId:    user.ID,
+		Change:    input.Change,
+		Reason:    input.Reason,
+		Note:      strings.TrimSpace(input.Note),
+	}
+
+	if !movement.ValidChange() {
+		return nil, errors.New("restock, return and cancellation must add stock, damage and sale must remove it and a correction can not be zero")
+	}
+
+	err = s.IRepo.AdjustStock(movement)
 	if err != nil {
 		return nil, err
 	}
 
-	return updatedProduct, nil
+	return s.Repo.FindProductById(id)
+}
+
+func (s CatalogService) SetReorderThreshold(id int, input dto.ReorderThresholdRequest, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	product.ReorderThreshold = input.Threshold
+	return s.Repo.EditProduct(product)
+}
+
+func (s CatalogService) SetProductSale(id int, input dto.ProductSaleRequest, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	salePrice, err := priceIn(input.SalePrice, product.Price.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	if !salePrice.IsPositive() || salePrice.Amount >= product.Price.Amount {
+		return nil, errors.New("sale price must be greater than 0 and lower than the price")
+	}
+
+	if input.StartsAt != nil && input.EndsAt != nil && !input.EndsAt.After(*input.StartsAt) {
+		return nil, errors.New("sale must end after it starts")
+	}
+
+	if input.EndsAt != nil && !input.EndsAt.After(time.Now()) {
+		return nil, errors.New("sale end must be in the future")
+	}
+
+	product.SalePrice = salePrice
+	product.SaleStartsAt = input.StartsAt
+	product.SaleEndsAt = input.EndsAt
+
+	return s.Repo.EditProduct(product)
+}
+
+func (s CatalogService) RemoveProductSale(id int, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	product.SalePrice = domain.Money{}
+	product.SaleStartsAt = nil
+	product.SaleEndsAt = nil
+
+	return s.Repo.EditProduct(product)
+}
+
+func (s CatalogService) GetPriceHistory(id int, days int, user domain.User) ([]*domain.PriceHistory, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if days <= 0 {
+		days = 30
+	}
+
+	return s.Repo.FindPriceHistory(product.ID, time.Now().AddDate(0, 0, -days))
+}
+
+func (s CatalogService) GetLowStockProducts(user domain.User) ([]*domain.Product, error) {
+	return s.Repo.FindSellerLowStockProducts(int(user.ID))
+}
+
+func (s CatalogService) GetStockMovements(id int, page dto.PaginationRequest, user domain.User) ([]*domain.InventoryMovement, *dto.PaginationResponse, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page.Normalize()
+
+	movements, total, err := s.IRepo.FindProductMovements(product.ID, page.Limit, page.Offset())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return movements, &dto.PaginationResponse{
+		Page:  page.Page,
+		Limit: page.Limit,
+		Total: total,
+	}, nil
 }
 
 func (s CatalogService) DeleteProduct(id int, user domain.User) error {
@@ -189,3 +443,673 @@ func (s CatalogService) DeleteProduct(id int, user domain.User) error {
 	}
 	return nil
 }
+
+// SetProductStatus moves a product between draft, published and unlisted.
+// Publishing with a future publish_at schedules it instead.
+func (s CatalogService) SetProductStatus(id int, input dto.ProductStatusRequest, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	switch input.Status {
+	case domain.ProductDraft:
+		product.Status = domain.ProductDraft
+		product.PublishAt = nil
+
+	case domain.ProductPublished, domain.ProductUnlisted:
+		err = s.validateForPublish(product)
+		if err != nil {
+			return nil, err
+		}
+
+		if input.Status == domain.ProductPublished && input.PublishAt != nil && input.PublishAt.After(time.Now()) {
+			product.Status = domain.ProductDraft
+			product.PublishAt = input.PublishAt
+		} else {
+			product.Status = input.Status
+			product.PublishAt = nil
+		}
+
+	default:
+		return nil, errors.New("status must be one of draft, published or unlisted")
+	}
+
+	return s.Repo.EditProduct(product)
+}
+
+// PublishScheduledProducts publishes the drafts whose publish time has
+// passed, drafts that no longer meet the requirements stay unpublished
+func (s CatalogService) PublishScheduledProducts(now time.Time) (int, error) {
+	products, err := s.Repo.FindProductsDueForPublish(now)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, product := range products {
+		product.PublishAt = nil
+
+		if err := s.validateForPublish(product); err != nil {
+			log.Printf("scheduled publish of product %v skipped: %v", product.ID, err)
+		} else {
+			product.Status = domain.ProductPublished
+			published++
+		}
+
+		if _, err := s.Repo.EditProduct(product); err != nil {
+			log.Printf("scheduled publish of product %v failed: %v", product.ID, err)
+		}
+	}
+
+	return published, nil
+}
+
+func (s CatalogService) validateForPublish(product *domain.Product) error {
+	err := product.CanPublish()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Repo.FindCategoryById(int(product.CategoryId))
+	if err != nil {
+		return errors.New("product category does not exist")
+	}
+
+	return nil
+}
+
+func (s CatalogService) ArchiveProduct(id int, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.ArchivedAt != nil {
+		return nil, errors.New("product is already archived")
+	}
+
+	now := time.Now()
+	product.ArchivedAt = &now
+	return s.Repo.EditProduct(product)
+}
+
+func (s CatalogService) UnarchiveProduct(id int, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.ArchivedAt == nil {
+		return nil, errors.New("product is not archived")
+	}
+
+	product.ArchivedAt = nil
+	return s.Repo.EditProduct(product)
+}
+
+// PurgeCatalog permanently removes products and categories that have been
+// deleted or archived for longer than the given number of days. Products
+// that appear in orders are always kept.
+func (s CatalogService) PurgeCatalog(days int) (*dto.PurgeResult, error) {
+	if days < 1 {
+		return nil, errors.New("days must be at least 1")
+	}
+
+	before := time.Now().AddDate(0, 0, -days)
+
+	products, err := s.Repo.FindPurgeableProducts(before)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.PurgeResult{}
+	for _, product := range products {
+		err = s.Repo.PurgeProduct(product.ID)
+		if err != nil {
+			log.Printf("purge product %v error: %v", product.ID, err)
+			continue
+		}
+
+		for _, image := range product.Images {
+			deleteStoredImage(s.Storage, image)
+		}
+		result.Products++
+	}
+
+	categories, err := s.Repo.FindPurgeableCategories(before)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, category := range categories {
+		err = s.Repo.PurgeCategory(category.ID)
+		if err != nil {
+			log.Printf("purge category %v error: %v", category.ID, err)
+			continue
+		}
+
+		deleteStoredImage(s.Storage, domain.ProductImage{StorageKey: category.ImageKey})
+		result.Categories++
+	}
+
+	return result, nil
+}
+
+func (s CatalogService) SetProductOptions(id int, input dto.SetProductOptionsRequest, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	var options []domain.ProductOption
+	seen := make(map[string]bool)
+	for i, o := range input.Options {
+		name := strings.TrimSpace(o.Name)
+		if len(name) == 0 || len(o.Values) == 0 {
+			return nil, errors.New("every option needs a name and at least one value")
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("option %v is defined more than once", name)
+		}
+		seen[name] = true
+
+		options = append(options, domain.ProductOption{
+			ProductId: product.ID,
+			Name:      name,
+			Values:    o.Values,
+			Position:  i,
+		})
+	}
+
+	for _, v := range product.Variants {
+		if err := validateVariantOptions(options, v.Options); err != nil {
+			return nil, fmt.Errorf("variant %v does not match the new options: %v", v.Sku, err)
+		}
+	}
+
+	err = s.Repo.ReplaceProductOptions(product.ID, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindProductById(id)
+}
+
+func (s CatalogService) CreateVariant(id int, input dto.CreateVariantRequest, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(strings.TrimSpace(input.Sku)) == 0 {
+		return nil, errors.New("sku is required")
+	}
+
+	err = s.validateNewVariant(product, 0, input.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := priceIn(input.Price, product.Price.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repo.CreateVariant(&domain.ProductVariant{
+		ProductId: product.ID,
+		UserId:    product.UserId,
+		Sku:       strings.TrimSpace(input.Sku),
+		Options:   input.Options,
+		Price:     price,
+		Stock:     input.Stock,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.syncProductStock(product)
+}
+
+func (s CatalogService) EditVariant(id int, variantId int, input dto.CreateVariantRequest, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	variant, ok := product.FindVariant(uint(variantId))
+	if !ok {
+		return nil, errors.New("variant does not exist")
+	}
+
+	if len(strings.TrimSpace(input.Sku)) > 0 {
+		variant.Sku = strings.TrimSpace(input.Sku)
+	}
+
+	if len(input.Options) > 0 {
+		err = s.validateNewVariant(product, variant.ID, input.Options)
+		if err != nil {
+			return nil, err
+		}
+		variant.Options = input.Options
+	}
+
+	if input.Price.IsPositive() {
+		variant.Price, err = priceIn(input.Price, product.Price.Currency)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = s.Repo.EditVariant(variant)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindProductById(id)
+}
+
+func (s CatalogService) DeleteVariant(id int, variantId int, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	variant, ok := product.FindVariant(uint(variantId))
+	if !ok {
+		return nil, errors.New("variant does not exist")
+	}
+
+	// the stock of the variant leaves the product with it
+	if variant.Stock > 0 {
+		err = s.IRepo.AdjustStock(&domain.InventoryMovement{
+			ProductId: product.ID,
+			VariantId: variant.ID,
+			UserId:    user.ID,
+			Change:    -int(variant.Stock),
+			Reason:    domain.MovementCorrection,
+			Note:      "variant deleted",
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.Repo.DeleteVariant(variantId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.syncProductStock(product)
+}
+
+// listingPrice checks the price of a new product is in a currency with an
+// exchange rate, the store currency is used when none is given
+func (s CatalogService) listingPrice(m domain.Money) (domain.Money, error) {
+	currency := strings.ToUpper(m.Currency)
+	if len(currency) == 0 {
+		currency = s.Config.Currency
+	}
+
+	rates, err := loadExchangeRates(s.Rates, s.Config.Currency)
+	if err != nil {
+		return domain.Money{}, err
+	}
+
+	if !rates.Supports(currency) {
+		return domain.Money{}, fmt.Errorf("currency %v is not supported", currency)
+	}
+
+	return priceIn(m, currency)
+}
+
+// showPricesIn adds the prices converted to the buyer's display currency,
+// products keep their own currency for checkout. Prices are shown in the
+// store currency when the display currency has no rate.
+func (s CatalogService) showPricesIn(currency string, products ...*domain.Product) error {
+	if len(currency) == 0 {
+		return nil
+	}
+
+	rates, err := loadExchangeRates(s.Rates, s.Config.Currency)
+	if err != nil {
+		return err
+	}
+
+	if !rates.Supports(currency) {
+		currency = s.Config.Currency
+	}
+
+	now := time.Now()
+	for _, p := range products {
+		price, err := rates.Convert(p.PriceAt(now), currency)
+		if err != nil {
+			return err
+		}
+		p.DisplayPrice = &price
+
+		lowest, err := rates.Convert(p.LowestPrice30d, currency)
+		if err != nil {
+			return err
+		}
+		p.DisplayLowestPrice30d = &lowest
+
+		for i := range p.Variants {
+			price, err := rates.Convert(p.Variants[i].PriceFor(*p, now), currency)
+			if err != nil {
+				return err
+			}
+			p.Variants[i].DisplayPrice = &price
+		}
+	}
+
+	return nil
+}
+
+// priceIn checks an amount given by a seller is in the expected currency,
+// amounts without currency are taken to be in it
+func priceIn(m domain.Money, currency string) (domain.Money, error) {
+	if len(m.Currency) == 0 {
+		m.Currency = currency
+	}
+
+	if !strings.EqualFold(m.Currency, currency) {
+		return domain.Money{}, fmt.Errorf("amounts must be given in %v", currency)
+	}
+	m.Currency = currency
+
+	if m.Amount < 0 {
+		return domain.Money{}, errors.New("amounts can not be negative")
+	}
+
+	return m, nil
+}
+
+func (s CatalogService) findOwnedProduct(id int, user domain.User) (*domain.Product, error) {
+	product, err := s.Repo.FindProductById(id)
+	if err != nil {
+		return nil, errors.New("product does not exist")
+	}
+
+	if product.UserId != int(user.ID) {
+		return nil, errors.New("you do not have manage rights of this product")
+	}
+
+	return product, nil
+}
+
+// validateNewVariant checks the option values and makes sure no other
+// variant of the product already uses the same combination
+func (s CatalogService) validateNewVariant(product *domain.Product, variantId uint, values map[string]string) error {
+	err := validateVariantOptions(product.Options, values)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range product.Variants {
+		if v.ID != variantId && reflect.DeepEqual(v.Options, values) {
+			return fmt.Errorf("variant %v already uses these options", v.Sku)
+		}
+	}
+
+	return nil
+}
+
+// syncProductStock keeps the product stock equal to the sum of its variants
+func (s CatalogService) syncProductStock(product *domain.Product) (*domain.Product, error) {
+	updated, err := s.Repo.FindProductById(int(product.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(updated.Variants) == 0 {
+		return updated, nil
+	}
+
+	var stock uint
+	for _, v := range updated.Variants {
+		stock += v.Stock
+	}
+	if stock == updated.Stock {
+		return updated, nil
+	}
+
+	// the stock the product had before its first variant was added
+	err = s.IRepo.AdjustStock(&domain.InventoryMovement{
+		ProductId: updated.ID,
+		UserId:    uint(updated.UserId),
+		Change:    int(stock) - int(updated.Stock),
+		Reason:    domain.MovementCorrection,
+		Note:      "stock is the sum of the variant stock",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindProductById(int(product.ID))
+}
+
+func validateVariantOptions(options []domain.ProductOption, values map[string]string) error {
+	if len(values) != len(options) {
+		return errors.New("a value must be given for every product option")
+	}
+
+	for _, o := range options {
+		value, ok := values[o.Name]
+		if !ok {
+			return fmt.Errorf("missing value for option %v", o.Name)
+		}
+		if !slices.Contains(o.Values, value) {
+			return fmt.Errorf("%v is not a valid value for option %v", value, o.Name)
+		}
+	}
+
+	return nil
+}
+
+func (s CatalogService) UploadProductImages(id int, files []*multipart.FileHeader, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("please provide at least one image")
+	}
+
+	if len(product.Images)+len(files) > maxProductImages {
+		return nil, fmt.Errorf("a product can have at most %v images", maxProductImages)
+	}
+
+	position := 0
+	for _, image := range product.Images {
+		position = max(position, image.Position+1)
+	}
+
+	var images []*domain.ProductImage
+	for i, file := range files {
+		image, err := storeImage(s.Storage, fmt.Sprintf("products/%v", product.ID), file, true)
+		if err != nil {
+			for _, stored := range images {
+				deleteStoredImage(s.Storage, *stored)
+			}
+			return nil, fmt.Errorf("%v: %v", file.Filename, err)
+		}
+
+		image.ProductId = product.ID
+		image.Position = position + i
+		images = append(images, image)
+	}
+
+	err = s.Repo.CreateProductImages(images)
+	if err != nil {
+		for _, stored := range images {
+			deleteStoredImage(s.Storage, *stored)
+		}
+		return nil, err
+	}
+
+	return s.syncProductImageUrl(product.ID)
+}
+
+func (s CatalogService) DeleteProductImage(id int, imageId int, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := s.Repo.FindProductImageById(imageId)
+	if err != nil || image.ProductId != product.ID {
+		return nil, errors.New("image does not exist")
+	}
+
+	// listed products must keep an image, see Product.CanPublish
+	listed := product.Status == domain.ProductPublished || product.Status == domain.ProductUnlisted
+	if listed && len(product.Images) == 1 {
+		return nil, errors.New("product needs at least one image, set it to draft before removing its last image")
+	}
+
+	err = s.Repo.DeleteProductImage(imageId)
+	if err != nil {
+		return nil, err
+	}
+	deleteStoredImage(s.Storage, *image)
+
+	return s.syncProductImageUrl(product.ID)
+}
+
+func (s CatalogService) ReorderProductImages(id int, input dto.ReorderImagesRequest, user domain.User) (*domain.Product, error) {
+	product, err := s.findOwnedProduct(id, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(input.ImageIds) != len(product.Images) {
+		return nil, errors.New("please provide the order of all product images")
+	}
+
+	for _, image := range product.Images {
+		if !slices.Contains(input.ImageIds, image.ID) {
+			return nil, errors.New("please provide the order of all product images")
+		}
+	}
+
+	err = s.Repo.UpdateProductImagePositions(product.ID, input.ImageIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.syncProductImageUrl(product.ID)
+}
+
+func (s CatalogService) UploadCategoryImage(id int, file *multipart.FileHeader) (*domain.Category, error) {
+	category, err := s.Repo.FindCategoryById(id)
+	if err != nil {
+		return nil, errors.New("category does not exist")
+	}
+
+	image, err := storeImage(s.Storage, fmt.Sprintf("categories/%v", category.ID), file, false)
+	if err != nil {
+		return nil, err
+	}
+
+	category.ImageUrl = image.Url
+	category.ImageKey = image.StorageKey
+	return s.Repo.EditCategory(category)
+}
+
+// storeImage validates the uploaded file and puts it, and optionally a
+// thumbnail of it, into storage under the given prefix
+func storeImage(store storage.Storage, prefix string, file *multipart.FileHeader, thumbnail bool) (*domain.ProductImage, error) {
+	if file.Size > helper.MaxImageSize {
+		return nil, errors.New("image must be smaller than 5 MB")
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return nil, errors.New("unable to read image")
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, helper.MaxImageSize+1))
+	if err != nil {
+		return nil, errors.New("unable to read image")
+	}
+
+	img, contentType, err := helper.ValidateImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := helper.RandomHex(16)
+	if err != nil {
+		return nil, errors.New("unable to store image")
+	}
+
+	image := &domain.ProductImage{
+		StorageKey:  fmt.Sprintf("%v/%v%v", prefix, name, helper.ImageExtension(contentType)),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+
+	image.Url, err = store.Put(image.StorageKey, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if !thumbnail {
+		return image, nil
+	}
+
+	var buf bytes.Buffer
+	err = helper.EncodeImage(&buf, helper.CreateThumbnail(img, helper.ThumbnailMaxWidth), contentType)
+	if err != nil {
+		deleteStoredImage(store, *image)
+		return nil, errors.New("unable to create thumbnail")
+	}
+
+	thumbExt := ".png"
+	if contentType == "image/jpeg" {
+		thumbExt = ".jpg"
+	}
+	image.ThumbnailKey = fmt.Sprintf("%v/%v_thumb%v", prefix, name, thumbExt)
+
+	image.ThumbnailUrl, err = store.Put(image.ThumbnailKey, &buf)
+	if err != nil {
+		deleteStoredImage(store, *image)
+		return nil, err
+	}
+
+	return image, nil
+}
+
+func deleteStoredImage(store storage.Storage, image domain.ProductImage) {
+	for _, key := range []string{image.StorageKey, image.ThumbnailKey} {
+		if len(key) == 0 {
+			continue
+		}
+		if err := store.Delete(key); err != nil {
+			log.Println("delete image error:", err)
+		}
+	}
+}
+
+// syncProductImageUrl keeps Product.ImageUrl pointing at the first gallery image
+func (s CatalogService) syncProductImageUrl(id uint) (*domain.Product, error) {
+	product, err := s.Repo.FindProductById(int(id))
+	if err != nil {
+		return nil, err
+	}
+
+	imageUrl := ""
+	if len(product.Images) > 0 {
+		imageUrl = product.Images[0].Url
+	}
+
+	if product.ImageUrl == imageUrl {
+		return product, nil
+	}
+
+	product.ImageUrl = imageUrl
+	return s.Repo.EditProduct(product)
+}
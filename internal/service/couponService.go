@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"strings"
+)
+
+type CouponService struct {
+	Repo   repository.CouponRepository
+	Auth   helper.Auth
+	Config config.AppConfig
+}
+
+func (s CouponService) GetCoupons() ([]*domain.Coupon, error) {
+	return s.Repo.FindCoupons()
+}
+
+func (s CouponService) CreateCoupon(input dto.CreateCouponRequest) (*domain.Coupon, error) {
+	coupon := &domain.Coupon{
+		Code:         strings.ToUpper(strings.TrimSpace(input.Code)),
+		Type:         input.Type,
+		Percent:      input.Percent,
+		Amount:       input.Amount,
+		MinOrder:     input.MinOrder,
+		ExpiresAt:    input.ExpiresAt,
+		UsageLimit:   input.UsageLimit,
+		PerUserLimit: input.PerUserLimit,
+		CategoryIds:  input.CategoryIds,
+		SellerIds:    input.SellerIds,
+		Active:       input.Active == nil || *input.Active,
+	}
+
+	err := validateCoupon(coupon, s.Config.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repo.CreateCoupon(coupon)
+	if err != nil {
+		return nil, err
+	}
+
+	return coupon, nil
+}
+
+func (s CouponService) EditCoupon(id uint, input dto.EditCouponRequest) (*domain.Coupon, error) {
+	coupon, err := s.Repo.FindCouponById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(input.Type) > 0 {
+		coupon.Type = input.Type
+	}
+
+	if input.Percent != nil {
+		coupon.Percent = *input.Percent
+	}
+
+	if input.Amount != nil {
+		coupon.Amount = *input.Amount
+	}
+
+	if input.MinOrder != nil {
+		coupon.MinOrder = *input.MinOrder
+	}
+
+	if input.NoExpiry {
+		coupon.ExpiresAt = nil
+	} else if input.ExpiresAt != nil {
+		coupon.ExpiresAt = input.ExpiresAt
+	}
+
+	if input.UsageLimit != nil {
+		coupon.UsageLimit = *input.UsageLimit
+	}
+
+	if input.PerUserLimit != nil {
+		coupon.PerUserLimit = *input.PerUserLimit
+	}
+
+	if input.CategoryIds != nil {
+		coupon.CategoryIds = input.CategoryIds
+	}
+
+	if input.SellerIds != nil {
+		coupon.SellerIds = input.SellerIds
+	}
+
+	if input.Active != nil {
+		coupon.Active = *input.Active
+	}
+
+	err = validateCoupon(coupon, s.Config.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.EditCoupon(coupon)
+}
+
+func validateCoupon(c *domain.Coupon, currency string) error {
+	if len(c.Code) < 3 {
+		return errors.New("coupon code must be at least 3 characters long")
+	}
+
+	var err error
+	c.Amount, err = priceIn(c.Amount, currency)
+	if err != nil {
+		return err
+	}
+
+	c.MinOrder, err = priceIn(c.MinOrder, currency)
+	if err != nil {
+		return err
+	}
+
+	switch c.Type {
+	case domain.CouponPercentage:
+		if c.Percent <= 0 || c.Percent > 100 {
+			return errors.New("percentage must be between 0 and 100")
+		}
+	case domain.CouponFixed:
+		if !c.Amount.IsPositive() {
+			return errors.New("amount must be greater than 0")
+		}
+	default:
+		return errors.New("coupon type must be percentage or fixed")
+	}
+
+	if c.UsageLimit < 0 || c.PerUserLimit < 0 {
+		return errors.New("limits can not be negative")
+	}
+
+	return nil
+}
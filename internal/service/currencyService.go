@@ -0,0 +1,130 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type CurrencyService struct {
+	Repo   repository.ExchangeRateRepository
+	Auth   helper.Auth
+	Config config.AppConfig
+}
+
+func (s CurrencyService) GetRates() ([]*domain.ExchangeRate, error) {
+	return s.Repo.FindRates()
+}
+
+func (s CurrencyService) UpdateRates(input dto.UpdateExchangeRatesRequest) ([]*domain.ExchangeRate, error) {
+	if len(input.Rates) == 0 {
+		return nil, errors.New("please provide at least one rate")
+	}
+
+	var rates []*domain.ExchangeRate
+	for currency, rate := range input.Rates {
+		exchangeRate, err := s.newRate(currency, rate)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, exchangeRate)
+	}
+
+	err := s.Repo.SaveRates(rates)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindRates()
+}
+
+// DeleteRate stops supporting the currency
+func (s CurrencyService) DeleteRate(currency string) ([]*domain.ExchangeRate, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == s.Config.Currency {
+		return nil, errors.New("the rate of the store currency can not be deleted")
+	}
+
+	err := s.Repo.DeleteRate(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindRates()
+}
+
+// ImportRates reads a csv file of currency,rate rows, a header row is skipped
+func (s CurrencyService) ImportRates(r io.Reader) ([]*domain.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the csv file: %v", err)
+	}
+
+	if len(records) > 0 && strings.EqualFold(records[0][0], "currency") {
+		records = records[1:]
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("csv file has no rates")
+	}
+
+	var rates []*domain.ExchangeRate
+	for i, record := range records {
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("row %v: rate must be a number", i+1)
+		}
+
+		exchangeRate, err := s.newRate(record[0], rate)
+		if err != nil {
+			return nil, fmt.Errorf("row %v: %v", i+1, err)
+		}
+		rates = append(rates, exchangeRate)
+	}
+
+	err = s.Repo.SaveRates(rates)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindRates()
+}
+
+func (s CurrencyService) newRate(currency string, rate float64) (*domain.ExchangeRate, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+
+	if len(currency) != 3 {
+		return nil, fmt.Errorf("%v is not an ISO 4217 currency code", currency)
+	}
+
+	if currency == s.Config.Currency {
+		return nil, fmt.Errorf("%v is the store currency, its rate is always 1", currency)
+	}
+
+	if rate <= 0 {
+		return nil, fmt.Errorf("rate of %v must be greater than 0", currency)
+	}
+
+	return &domain.ExchangeRate{Currency: currency, Rate: rate}, nil
+}
+
+func loadExchangeRates(repo repository.ExchangeRateRepository, base string) (domain.ExchangeRates, error) {
+	rates, err := repo.FindRates()
+	if err != nil {
+		return domain.ExchangeRates{}, err
+	}
+
+	return domain.NewExchangeRates(base, rates), nil
+}
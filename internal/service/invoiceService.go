@@ -0,0 +1,291 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/pdf"
+	"go-ecommerce-app/pkg/storage"
+	"io"
+	"log"
+	"strings"
+)
+
+type InvoiceService struct {
+	Repo     repository.InvoiceRepository
+	UserRepo repository.UserRepository
+	Storage  storage.Storage
+	Auth     helper.Auth
+	Config   config.AppConfig
+}
+
+func (s InvoiceService) GetOrderInvoices(orderId uint, u domain.User) ([]*domain.Invoice, error) {
+	order, err := s.UserRepo.FindOrderById(orderId, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindOrderInvoices(order.ID)
+}
+
+func (s InvoiceService) GetSellerInvoices(page dto.PaginationRequest, seller domain.User) ([]*domain.Invoice, *dto.PaginationResponse, error) {
+	page.Normalize()
+
+	invoices, total, err := s.Repo.FindSellerInvoices(seller.ID, page.Limit, page.Offset())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return invoices, &dto.PaginationResponse{
+		Page:  page.Page,
+		Limit: page.Limit,
+		Total: total,
+	}, nil
+}
+
+// DownloadInvoice returns the pdf of one of the buyer's invoices
+func (s InvoiceService) DownloadInvoice(id uint, u domain.User) (*domain.Invoice, []byte, error) {
+	invoice, err := s.Repo.FindInvoiceById(id)
+	if err != nil || invoice.UserId != u.ID {
+		return nil, nil, errors.New("invoice does not exist")
+	}
+
+	data, err := s.invoicePdf(invoice)
+	return invoice, data, err
+}
+
+// DownloadSellerInvoice returns the pdf of one of the seller's invoices
+func (s InvoiceService) DownloadSellerInvoice(id uint, seller domain.User) (*domain.Invoice, []byte, error) {
+	invoice, err := s.Repo.FindInvoiceById(id)
+	if err != nil || invoice.SellerId != seller.ID {
+		return nil, nil, errors.New("invoice does not exist")
+	}
+
+	data, err := s.invoicePdf(invoice)
+	return invoice, data, err
+}
+
+// StoreOrderInvoices renders and stores the invoices issued for a paid
+// order. Invoices that fail are rendered when first downloaded instead.
+func (s InvoiceService) StoreOrderInvoices(orderId uint) {
+	invoices, err := s.Repo.FindOrderInvoices(orderId)
+	if err != nil {
+		log.Printf("invoices of order %v could not be found: %v", orderId, err)
+		return
+	}
+
+	for _, invoice := range invoices {
+		if _, err := s.invoicePdf(invoice); err != nil {
+			log.Printf("invoice %v could not be stored: %v", invoice.Code, err)
+		}
+	}
+}
+
+// invoicePdf reads the stored pdf of the invoice, rendering and storing it
+// the first time
+func (s InvoiceService) invoicePdf(invoice *domain.Invoice) ([]byte, error) {
+	if len(invoice.StorageKey) > 0 {
+		f, err := s.Storage.Get(invoice.StorageKey)
+		if err == nil {
+			defer f.Close()
+			return io.ReadAll(f)
+		}
+		log.Printf("stored invoice %v could not be read, rendering it again: %v", invoice.Code, err)
+	}
+
+	data, err := s.renderInvoice(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	// invoices are only handed out through the api, the key must not be guessable
+	name, err := helper.RandomHex(16)
+	if err != nil {
+		return nil, errors.New("unable to store invoice")
+	}
+	key := fmt.Sprintf("invoices/%v/%v_%v", invoice.SellerId, name, invoice.FileName())
+
+	_, err = s.Storage.Put(key, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repo.SetInvoiceFile(invoice.ID, key)
+	if err != nil {
+		return nil, err
+	}
+	invoice.StorageKey = key
+
+	return data, nil
+}
+
+func (s InvoiceService) renderInvoice(invoice *domain.Invoice) ([]byte, error) {
+	order, err := s.UserRepo.FindSellerOrderById(invoice.OrderId, invoice.SellerId)
+	if err != nil {
+		return nil, err
+	}
+
+	var shipment *domain.Shipment
+	for i := range order.Shipments {
+		if order.Shipments[i].ID == invoice.ShipmentId {
+			shipment = &order.Shipments[i]
+		}
+	}
+	if shipment == nil {
+		return nil, errors.New("invoice shipment does not exist")
+	}
+
+	seller, err := s.UserRepo.FindUserById(invoice.SellerId)
+	if err != nil {
+		return nil, err
+	}
+
+	buyer, err := s.UserRepo.FindUserById(invoice.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := pdf.New()
+	const left, right = 50.0, pdf.PageWidth - 50
+
+	doc.Text(left, 70, 22, true, "INVOICE")
+	doc.TextRight(right, 60, 10, true, invoice.Code)
+	doc.TextRight(right, 75, 10, false, "Issued "+invoice.IssuedAt.Format("2 January 2006"))
+	doc.TextRight(right, 90, 10, false, fmt.Sprintf("Order %v", order.ID))
+
+	y := 130.0
+	doc.Text(left, y, 10, true, "Sold by")
+	doc.Text(300, y, 10, true, "Bill to")
+	sellerLines := []string{
+		strings.TrimSpace(seller.FirstName + " " + seller.LastName),
+		seller.Email,
+		seller.Phone,
+	}
+	buyerLines := []string{
+		order.ShipTo.Name,
+		order.ShipTo.Line1,
+		order.ShipTo.Line2,
+		strings.TrimSpace(order.ShipTo.PostCode + " " + order.ShipTo.City),
+		strings.TrimSpace(order.ShipTo.Region + " " + order.ShipTo.Country),
+		buyer.Email,
+	}
+	sy := y
+	for _, line := range sellerLines {
+		if len(line) > 0 {
+			sy += 14
+			doc.Text(left, sy, 10, false, line)
+		}
+	}
+	by := y
+	for _, line := range buyerLines {
+		if len(line) > 0 {
+			by += 14
+			doc.Text(300, by, 10, false, line)
+		}
+	}
+	y = max(sy, by) + 40
+
+	columns := []float64{330, 380, 440, 490, right}
+	header := func() {
+		doc.Text(left, y, 9, true, "Item")
+		for i, title := range []string{"Qty", "Unit price", "Discount", "Tax", "Total"} {
+			doc.TextRight(columns[i], y, 9, true, title)
+		}
+		doc.Line(left, y+5, right, y+5)
+		y += 20
+	}
+	header()
+
+	type taxLine struct {
+		name   string
+		amount int64
+	}
+	var taxes []*taxLine
+	byName := make(map[string]*taxLine)
+
+	for _, item := range order.Items {
+		if y > pdf.PageHeight-80 {
+			doc.AddPage()
+			y = 70
+			header()
+		}
+
+		name := item.Name
+		if len(item.Sku) > 0 {
+			name = fmt.Sprintf("%v (%v)", item.Name, item.Sku)
+		}
+		if len(name) > 48 {
+			name = name[:45] + "..."
+		}
+
+		doc.Text(left, y, 9, false, name)
+		doc.TextRight(columns[0], y, 9, false, fmt.Sprintf("%v", item.Qty))
+		doc.TextRight(columns[1], y, 9, false, item.Price.Decimal())
+		doc.TextRight(columns[2], y, 9, false, item.Discount.Decimal())
+		doc.TextRight(columns[3], y, 9, false, item.Tax.Decimal())
+		doc.TextRight(columns[4], y, 9, false, item.Paid().Decimal())
+		y += 16
+
+		for _, tax := range item.Taxes {
+			label := fmt.Sprintf("%v %v%%", tax.Name, tax.Rate)
+			if tax.Inclusive {
+				label += " (included)"
+			}
+			t, ok := byName[label]
+			if !ok {
+				t = &taxLine{name: label}
+				byName[label] = t
+				taxes = append(taxes, t)
+			}
+			t.amount += tax.Amount.Amount
+		}
+	}
+
+	if y > pdf.PageHeight-200 {
+		doc.AddPage()
+		y = 70
+	}
+
+	doc.Line(left, y-6, right, y-6)
+	y += 10
+	currency := shipment.Amount.Currency
+	totals := [][2]string{
+		{"Subtotal", shipment.Subtotal.Decimal()},
+	}
+	if shipment.Discount.IsPositive() {
+		totals = append(totals, [2]string{"Discount", "-" + shipment.Discount.Decimal()})
+	}
+	totals = append(totals, [2]string{"Shipping", shipment.ShippingPrice.Decimal()})
+	for _, t := range taxes {
+		totals = append(totals, [2]string{t.name, domain.NewMoney(t.amount, currency).Decimal()})
+	}
+	for _, t := range totals {
+		doc.TextRight(columns[3], y, 10, false, t[0])
+		doc.TextRight(right, y, 10, false, t[1])
+		y += 16
+	}
+	doc.TextRight(columns[3], y+4, 11, true, "Total "+currency)
+	doc.TextRight(right, y+4, 11, true, shipment.Amount.Decimal())
+
+	if order.PaidAt != nil {
+		doc.Text(left, y+40, 9, false, "Paid on "+order.PaidAt.Format("2 January 2006")+", transaction "+order.TransactionId)
+	}
+
+	if invoice.CreditedAt != nil {
+		doc.Text(left, y+56, 9, true, fmt.Sprintf("Cancelled on %v by credit note %v of -%v %v",
+			invoice.CreditedAt.Format("2 January 2006"), invoice.CreditNote, shipment.Amount.Decimal(), currency))
+	}
+
+	var buf bytes.Buffer
+	_, err = doc.WriteTo(&buf)
+	if err != nil {
+		return nil, errors.New("unable to render invoice")
+	}
+
+	return buf.Bytes(), nil
+}
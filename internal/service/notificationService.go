@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/notification"
+	"log"
+	"time"
+)
+
+const (
+	notificationBatchSize   = 50
+	maxNotificationAttempts = 5
+)
+
+type NotificationService struct {
+	Repo   repository.NotificationRepository
+	URepo  repository.UserRepository
+	Config config.AppConfig
+}
+
+func (s NotificationService) Queue(userId uint, message string) error {
+	return s.Repo.CreateNotification(&domain.Notification{
+		UserId:  userId,
+		Message: message,
+	})
+}
+
+// DeliverPending sends queued notifications by sms, failed messages are
+// retried on the next run until they run out of attempts
+func (s NotificationService) DeliverPending() error {
+	notifications, err := s.Repo.FindPendingNotifications(notificationBatchSize)
+	if err != nil {
+		return err
+	}
+
+	notificationClient := notification.NewNotificationClient(s.Config)
+
+	for _, n := range notifications {
+		n.Attempts++
+
+		user, err := s.URepo.FindUserById(n.UserId)
+		if err == nil && len(user.Phone) == 0 {
+			err = errors.New("user has no phone number")
+		}
+		if err == nil {
+			err = notificationClient.SendSMS(user.Phone, n.Message)
+		}
+
+		if err != nil {
+			n.LastError = err.Error()
+			if n.Attempts >= maxNotificationAttempts {
+				n.Status = domain.NotificationFailed
+			}
+		} else {
+			now := time.Now()
+			n.Status = domain.NotificationSent
+			n.SentAt = &now
+		}
+
+		if err := s.Repo.UpdateNotification(n); err != nil {
+			log.Println("update notification error:", err)
+		}
+	}
+
+	return nil
+}
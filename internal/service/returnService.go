@@ -0,0 +1,309 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/payment"
+	"go-ecommerce-app/pkg/storage"
+	"io"
+	"log"
+	"mime/multipart"
+	"strings"
+	"time"
+)
+
+const (
+	maxReturnPhotos       = 5
+	maxReturnReasonLength = 1000
+)
+
+// photos of returns are downloaded under the routes of buyers and sellers
+const (
+	buyerReturnsUrl  = "/users"
+	sellerReturnsUrl = "/seller"
+)
+
+type ReturnService struct {
+	Repo     repository.ReturnRepository
+	UserRepo repository.UserRepository
+	Storage  storage.Storage
+	Auth     helper.Auth
+	Config   config.AppConfig
+}
+
+// CreateReturn asks the seller to take back units of a delivered order item
+func (s ReturnService) CreateReturn(orderId uint, input dto.CreateReturnRequest, photos []*multipart.FileHeader, u domain.User) (*domain.ReturnRequest, error) {
+	order, err := s.UserRepo.FindOrderById(orderId, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var item *domain.OrderItem
+	for i := range order.Items {
+		if order.Items[i].ID == input.OrderItemId {
+			item = &order.Items[i]
+		}
+	}
+	if item == nil {
+		return nil, errors.New("item is not part of this order")
+	}
+
+	var shipment *domain.Shipment
+	for i := range order.Shipments {
+		if order.Shipments[i].SellerId == item.SellerId {
+			shipment = &order.Shipments[i]
+		}
+	}
+	if shipment == nil || shipment.Status != domain.OrderDelivered || shipment.DeliveredAt == nil {
+		return nil, errors.New("items can be returned once they are delivered")
+	}
+	if time.Since(*shipment.DeliveredAt) > domain.ReturnWindow {
+		return nil, errors.New("the return period for this item has ended")
+	}
+
+	reason := strings.TrimSpace(input.Reason)
+	if len(reason) == 0 {
+		return nil, errors.New("please tell us why you are returning the item")
+	}
+	if len(reason) > maxReturnReasonLength {
+		return nil, fmt.Errorf("reason must be at most %v characters", maxReturnReasonLength)
+	}
+
+	if len(photos) > maxReturnPhotos {
+		return nil, fmt.Errorf("at most %v photos can be added", maxReturnPhotos)
+	}
+
+	returned, err := s.Repo.CountReturnedQty(item.ID)
+	if err != nil {
+		return nil, err
+	}
+	if input.Qty == 0 || returned+input.Qty > item.Qty {
+		return nil, fmt.Errorf("up to %v units of this item can be returned", item.Qty-min(returned, item.Qty))
+	}
+
+	e := &domain.ReturnRequest{
+		OrderId:      order.ID,
+		OrderItemId:  item.ID,
+		ShipmentId:   shipment.ID,
+		UserId:       u.ID,
+		SellerId:     item.SellerId,
+		ProductId:    item.ProductId,
+		VariantId:    item.VariantId,
+		Name:         item.Name,
+		Qty:          input.Qty,
+		Reason:       reason,
+		Status:       domain.ReturnRequested,
+		RefundAmount: item.Paid().Share(int64(input.Qty), int64(item.Qty)),
+	}
+
+	for _, file := range photos {
+		photo, err := storeImage(s.Storage, fmt.Sprintf("returns/%v", order.ID), file, false)
+		if err != nil {
+			s.deletePhotos(e)
+			return nil, err
+		}
+		e.PhotoKeys = append(e.PhotoKeys, photo.StorageKey)
+	}
+
+	err = s.Repo.CreateReturn(e)
+	if err != nil {
+		s.deletePhotos(e)
+		return nil, err
+	}
+
+	e.SetPhotoUrls(buyerReturnsUrl)
+	return e, nil
+}
+
+func (s ReturnService) GetUserReturns(u domain.User) ([]*domain.ReturnRequest, error) {
+	returns, err := s.Repo.FindUserReturns(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range returns {
+		e.SetPhotoUrls(buyerReturnsUrl)
+	}
+
+	return returns, nil
+}
+
+func (s ReturnService) GetSellerReturns(status string, page dto.PaginationRequest, seller domain.User) ([]*domain.ReturnRequest, *dto.PaginationResponse, error) {
+	page.Normalize()
+
+	returns, total, err := s.Repo.FindSellerReturns(seller.ID, status, page.Limit, page.Offset())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, e := range returns {
+		e.SetPhotoUrls(sellerReturnsUrl)
+	}
+
+	return returns, &dto.PaginationResponse{
+		Page:  page.Page,
+		Limit: page.Limit,
+		Total: total,
+	}, nil
+}
+
+// ReviewReturn approves or rejects a requested return
+func (s ReturnService) ReviewReturn(id uint, input dto.ReviewReturnRequest, seller domain.User) (*domain.ReturnRequest, error) {
+	e, err := s.findSellerReturn(id, seller)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Status != domain.ReturnApproved && input.Status != domain.ReturnRejected {
+		return nil, errors.New("status must be approved or rejected")
+	}
+
+	if !e.CanMoveTo(input.Status) {
+		return nil, fmt.Errorf("return can not be moved from %v to %v", e.Status, input.Status)
+	}
+
+	e.SellerNote = strings.TrimSpace(input.Note)
+	err = s.Repo.UpdateReturnStatus(e, input.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// ReceiveReturn restocks the returned units and refunds the buyer
+func (s ReturnService) ReceiveReturn(id uint, seller domain.User) (*domain.ReturnRequest, error) {
+	e, err := s.findSellerReturn(id, seller)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.CanMoveTo(domain.ReturnReceived) {
+		return nil, fmt.Errorf("return can not be received while %v", e.Status)
+	}
+
+	err = s.Repo.ReceiveReturn(e, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refund(e)
+}
+
+// RefundReturn retries the refund of a received return, or of one whose
+// refund did not go through
+func (s ReturnService) RefundReturn(id uint, seller domain.User) (*domain.ReturnRequest, error) {
+	e, err := s.findSellerReturn(id, seller)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Status != domain.ReturnReceived && e.Status != domain.ReturnRefunding {
+		return nil, fmt.Errorf("return can not be refunded while %v", e.Status)
+	}
+
+	return s.refund(e)
+}
+
+// refund claims the return and then refunds it through the gateway. The
+// return id is the idempotency key, so retrying a claimed return can not
+// refund the buyer twice.
+func (s ReturnService) refund(e *domain.ReturnRequest) (*domain.ReturnRequest, error) {
+	order, err := s.UserRepo.FindOrderById(e.OrderId, e.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Status == domain.ReturnReceived {
+		err = s.Repo.ClaimReturnRefund(e)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// orders fully covered by a coupon were never charged
+	refundId := ""
+	if e.RefundAmount.IsPositive() {
+		paymentClient, err := payment.NewPaymentClient(s.Config)
+		if err != nil {
+			return nil, err
+		}
+		refundId, err = paymentClient.Refund(order.TransactionId, e.RefundAmount.Amount, e.RefundAmount.Currency, fmt.Sprintf("return_%v", e.ID))
+		if err != nil {
+			log.Printf("refund of return %v failed: %v", e.ID, err)
+			return nil, fmt.Errorf("return was received but the refund failed, please try again: %v", err)
+		}
+	}
+
+	err = s.Repo.RefundReturn(e, refundId, time.Now())
+	if err != nil {
+		log.Printf("refund %v of return %v was made but not recorded: %v", refundId, e.ID, err)
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (s ReturnService) findSellerReturn(id uint, seller domain.User) (*domain.ReturnRequest, error) {
+	e, err := s.Repo.FindReturnById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.SellerId != seller.ID {
+		return nil, errors.New("return request does not exist")
+	}
+
+	e.SetPhotoUrls(sellerReturnsUrl)
+	return e, nil
+}
+
+// GetUserReturnPhoto returns a photo of one of the buyer's returns
+func (s ReturnService) GetUserReturnPhoto(id uint, n int, u domain.User) ([]byte, error) {
+	e, err := s.Repo.FindReturnById(id)
+	if err != nil || e.UserId != u.ID {
+		return nil, errors.New("return request does not exist")
+	}
+
+	return s.readPhoto(e, n)
+}
+
+// GetSellerReturnPhoto returns a photo of a return of the seller
+func (s ReturnService) GetSellerReturnPhoto(id uint, n int, seller domain.User) ([]byte, error) {
+	e, err := s.findSellerReturn(id, seller)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.readPhoto(e, n)
+}
+
+func (s ReturnService) readPhoto(e *domain.ReturnRequest, n int) ([]byte, error) {
+	if n < 0 || n >= len(e.PhotoKeys) {
+		return nil, errors.New("photo does not exist")
+	}
+
+	f, err := s.Storage.Get(e.PhotoKeys[n])
+	if err != nil {
+		return nil, errors.New("photo could not be read")
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, errors.New("photo could not be read")
+	}
+
+	return data, nil
+}
+
+func (s ReturnService) deletePhotos(e *domain.ReturnRequest) {
+	for _, key := range e.PhotoKeys {
+		deleteStoredImage(s.Storage, domain.ProductImage{StorageKey: key})
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"strings"
+	"time"
+)
+
+const maxReviewLength = 2000
+
+type ReviewService struct {
+	Repo   repository.ReviewRepository
+	CRepo  repository.CatalogRepository
+	Auth   helper.Auth
+	Config config.AppConfig
+}
+
+func (s ReviewService) GetProductReviews(productId uint, page dto.PaginationRequest) ([]*domain.Review, *dto.PaginationResponse, error) {
+	page.Normalize()
+
+	reviews, total, err := s.Repo.FindProductReviews(productId, page.Limit, page.Offset())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return reviews, &dto.PaginationResponse{
+		Page:  page.Page,
+		Limit: page.Limit,
+		Total: total,
+	}, nil
+}
+
+func (s ReviewService) CreateReview(productId uint, input dto.CreateReviewRequest, user domain.User) (*domain.Review, error) {
+	if input.Rating < 1 || input.Rating > 5 {
+		return nil, errors.New("rating must be between 1 and 5")
+	}
+
+	comment := strings.TrimSpace(input.Comment)
+	if len(comment) > maxReviewLength {
+		return nil, errors.New("review is too long")
+	}
+
+	_, err := s.CRepo.FindProductById(int(productId))
+	if err != nil {
+		return nil, errors.New("product does not exist")
+	}
+
+	// only buyers who received the product can review it
+	item, err := s.Repo.FindDeliveredOrderItem(user.ID, productId)
+	if err != nil {
+		return nil, errors.New("you can only review products from your delivered orders")
+	}
+
+	review := &domain.Review{
+		ProductId: productId,
+		UserId:    user.ID,
+		OrderId:   item.OrderId,
+		Rating:    input.Rating,
+		Comment:   comment,
+	}
+
+	err = s.Repo.CreateReview(review)
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+func (s ReviewService) ReplyToReview(id uint, input dto.ReviewReplyRequest, user domain.User) (*domain.Review, error) {
+	reply := strings.TrimSpace(input.Reply)
+	if len(reply) == 0 {
+		return nil, errors.New("reply can not be empty")
+	}
+	if len(reply) > maxReviewLength {
+		return nil, errors.New("reply is too long")
+	}
+
+	review, err := s.Repo.FindReviewById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err := s.CRepo.FindProductById(int(review.ProductId))
+	if err != nil || product.UserId != int(user.ID) {
+		return nil, errors.New("you can only reply to reviews of your own products")
+	}
+
+	if len(review.Reply) > 0 {
+		return nil, errors.New("this review already has a reply")
+	}
+
+	now := time.Now()
+	review.Reply = reply
+	review.RepliedAt = &now
+
+	return s.Repo.UpdateReview(review)
+}
+
+// SetReviewHidden lets admins take abusive reviews out of listings and ratings
+func (s ReviewService) SetReviewHidden(id uint, input dto.HideReviewRequest) (*domain.Review, error) {
+	review, err := s.Repo.FindReviewById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	review.Hidden = input.Hidden
+	review.HiddenReason = ""
+	if input.Hidden {
+		review.HiddenReason = strings.TrimSpace(input.Reason)
+	}
+
+	return s.Repo.UpdateReview(review)
+}
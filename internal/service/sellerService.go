@@ -0,0 +1,448 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"go-ecommerce-app/pkg/storage"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type SellerService struct {
+	Repo      repository.SellerRepository
+	UserRepo  repository.UserRepository
+	Catalog   CatalogService
+	Rates     repository.ExchangeRateRepository
+	Storage   storage.Storage
+	Documents storage.Storage // private, application documents
+	Auth      helper.Auth
+	Config    config.AppConfig
+}
+
+const maxDocumentSize = 10 << 20 // 10 MB
+
+// documentExtensions lists the file types accepted as application documents
+var documentExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}
+
+// GetStorefront returns the public profile of a seller, sellers who never
+// set one up are shown under their name
+func (s SellerService) GetStorefront(sellerId uint) (*domain.SellerProfile, error) {
+	seller, err := s.findSeller(sellerId)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := s.Repo.FindSellerProfile(seller.ID)
+	if err != nil {
+		profile = &domain.SellerProfile{
+			UserId:      seller.ID,
+			DisplayName: strings.TrimSpace(seller.FirstName + " " + seller.LastName),
+		}
+	}
+	profile.MemberSince = seller.CreatedAt
+
+	profile.Rating, profile.ReviewCount, err = s.Repo.FindSellerRating(seller.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+// GetStorefrontProducts lists the published products of a seller
+func (s SellerService) GetStorefrontProducts(sellerId uint, page dto.PaginationRequest, currency string) ([]*domain.Product, *dto.PaginationResponse, error) {
+	seller, err := s.findSeller(sellerId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page.Normalize()
+
+	products, total, err := s.Catalog.Repo.FindSellerProducts(int(seller.ID), true, page.Limit, page.Offset())
+	if err != nil {
+		return nil, nil, errors.New("unable to load seller products")
+	}
+
+	err = s.Catalog.showPricesIn(currency, products...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return products, &dto.PaginationResponse{
+		Page:  page.Page,
+		Limit: page.Limit,
+		Total: total,
+	}, nil
+}
+
+func (s SellerService) GetSellerProfile(seller domain.User) (*domain.SellerProfile, error) {
+	return s.GetStorefront(seller.ID)
+}
+
+func (s SellerService) UpdateSellerProfile(input dto.SellerProfileRequest, seller domain.User) (*domain.SellerProfile, error) {
+	name := strings.TrimSpace(input.DisplayName)
+	if len(name) == 0 || len(name) > 80 {
+		return nil, errors.New("display name must be between 1 and 80 characters")
+	}
+	if len(input.Description) > 2000 {
+		return nil, errors.New("description must be at most 2000 characters")
+	}
+	if len(input.ReturnPolicy) > 5000 || len(input.ShippingPolicy) > 5000 {
+		return nil, errors.New("policies must be at most 5000 characters")
+	}
+
+	profile, err := s.Repo.FindSellerProfile(seller.ID)
+	if err != nil {
+		profile = &domain.SellerProfile{UserId: seller.ID}
+	}
+
+	profile.DisplayName = name
+	profile.Description = strings.TrimSpace(input.Description)
+	profile.ReturnPolicy = strings.TrimSpace(input.ReturnPolicy)
+	profile.ShippingPolicy = strings.TrimSpace(input.ShippingPolicy)
+
+	err = s.Repo.SaveSellerProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetStorefront(seller.ID)
+}
+
+// UploadSellerLogo replaces the logo of the seller, the profile has to
+// exist first so it has a display name
+func (s SellerService) UploadSellerLogo(file *multipart.FileHeader, seller domain.User) (*domain.SellerProfile, error) {
+	profile, err := s.Repo.FindSellerProfile(seller.ID)
+	if err != nil {
+		return nil, errors.New("please set up your seller profile first")
+	}
+
+	image, err := storeImage(s.Storage, fmt.Sprintf("sellers/%v", seller.ID), file, false)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := profile.LogoKey
+	profile.LogoUrl = image.Url
+	profile.LogoKey = image.StorageKey
+
+	err = s.Repo.SaveSellerProfile(profile)
+	if err != nil {
+		deleteStoredImage(s.Storage, *image)
+		return nil, err
+	}
+
+	if len(previous) > 0 {
+		deleteStoredImage(s.Storage, domain.ProductImage{StorageKey: previous})
+	}
+
+	return s.GetStorefront(seller.ID)
+}
+
+func (s SellerService) findSeller(id uint) (domain.User, error) {
+	seller, err := s.UserRepo.FindUserById(id)
+	if err != nil || seller.UserType != domain.SELLER {
+		return domain.User{}, errors.New("seller does not exist")
+	}
+
+	return seller, nil
+}
+
+// BecomeSeller files an application to join the seller program, the user
+// becomes a seller once an admin approves it
+func (s SellerService) BecomeSeller(input dto.SellerInput, documents map[string][]*multipart.FileHeader, u domain.User) (*domain.SellerApplication, error) {
+	user, err := s.UserRepo.FindUserById(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.UserType == domain.SELLER {
+		return nil, errors.New("you have already joined the seller program")
+	}
+
+	if !user.Verified || len(user.Phone) == 0 {
+		return nil, errors.New("please verify your phone number before applying")
+	}
+
+	phone := strings.TrimSpace(input.PhoneNumber)
+	if len(phone) > 0 && phone != user.Phone {
+		return nil, errors.New("phone number must be the verified phone of your account")
+	}
+
+	if existing, err := s.Repo.FindUserSellerApplication(user.ID); err == nil && existing.Status == domain.ApplicationPending {
+		return nil, errors.New("your seller application is already being reviewed")
+	}
+
+	application := &domain.SellerApplication{
+		UserId:             user.ID,
+		Status:             domain.ApplicationPending,
+		FirstName:          strings.TrimSpace(input.FirstName),
+		LastName:           strings.TrimSpace(input.LastName),
+		Phone:              user.Phone,
+		BusinessName:       strings.TrimSpace(input.BusinessName),
+		BusinessType:       strings.ToLower(strings.TrimSpace(input.BusinessType)),
+		RegistrationNumber: strings.TrimSpace(input.RegistrationNumber),
+		TaxId:              strings.TrimSpace(input.TaxId),
+		Address: domain.PostalAddress{
+			Name:     strings.TrimSpace(input.BusinessName),
+			Line1:    strings.TrimSpace(input.AddressLine1),
+			Line2:    strings.TrimSpace(input.AddressLine2),
+			City:     strings.TrimSpace(input.City),
+			PostCode: strings.TrimSpace(input.PostCode),
+			Region:   strings.ToUpper(strings.TrimSpace(input.Region)),
+			Country:  strings.ToUpper(strings.TrimSpace(input.Country)),
+			Phone:    user.Phone,
+		},
+		BankAccount: input.BankAccountNumber,
+		SwiftCode:   strings.TrimSpace(input.SwiftCode),
+		PaymentType: strings.TrimSpace(input.PaymentType),
+		Currency:    strings.ToUpper(strings.TrimSpace(input.Currency)),
+	}
+
+	err = s.validateApplication(application)
+	if err != nil {
+		return nil, err
+	}
+
+	required := []string{domain.DocumentIdentity}
+	if application.BusinessType == domain.BusinessCompany {
+		required = append(required, domain.DocumentBusinessRegistration)
+	}
+	for _, docType := range required {
+		if len(documents[docType]) == 0 {
+			return nil, fmt.Errorf("please upload the %v document", strings.ReplaceAll(docType, "_", " "))
+		}
+	}
+
+	for _, docType := range []string{domain.DocumentIdentity, domain.DocumentBusinessRegistration, domain.DocumentProofOfAddress} {
+		files := documents[docType]
+		if len(files) > 1 {
+			s.deleteDocuments(application)
+			return nil, fmt.Errorf("only one %v document can be uploaded", strings.ReplaceAll(docType, "_", " "))
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		doc, err := storeDocument(s.Documents, fmt.Sprintf("sellers/%v/applications", user.ID), docType, files[0])
+		if err != nil {
+			s.deleteDocuments(application)
+			return nil, err
+		}
+		application.Documents = append(application.Documents, *doc)
+	}
+
+	err = s.Repo.CreateSellerApplication(application)
+	if err != nil {
+		s.deleteDocuments(application)
+		return nil, err
+	}
+
+	return application, nil
+}
+
+// GetSellerApplication returns the latest application of the user, and a
+// seller token once it has been approved
+func (s SellerService) GetSellerApplication(u domain.User) (*domain.SellerApplication, string, error) {
+	application, err := s.Repo.FindUserSellerApplication(u.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if application.Status != domain.ApplicationApproved {
+		return application, "", nil
+	}
+
+	user, err := s.UserRepo.FindUserById(u.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := s.Auth.GenerateToken(user.ID, user.Email, user.UserType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return application, token, nil
+}
+
+func (s SellerService) GetSellerApplications(status string, page dto.PaginationRequest) ([]*domain.SellerApplication, *dto.PaginationResponse, error) {
+	page.Normalize()
+
+	applications, total, err := s.Repo.FindSellerApplications(status, page.Limit, page.Offset())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return applications, &dto.PaginationResponse{
+		Page:  page.Page,
+		Limit: page.Limit,
+		Total: total,
+	}, nil
+}
+
+func (s SellerService) GetSellerApplicationById(id uint) (*domain.SellerApplication, error) {
+	return s.Repo.FindSellerApplicationById(id)
+}
+
+// DownloadSellerDocument returns a document of an application for review
+func (s SellerService) DownloadSellerDocument(id uint, documentId uint) (*domain.SellerDocument, []byte, error) {
+	application, err := s.Repo.FindSellerApplicationById(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, doc := range application.Documents {
+		if doc.ID != documentId {
+			continue
+		}
+
+		f, err := s.Documents.Get(doc.StorageKey)
+		if err != nil {
+			return nil, nil, errors.New("document could not be read")
+		}
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil, nil, errors.New("document could not be read")
+		}
+
+		return &doc, data, nil
+	}
+
+	return nil, nil, errors.New("document does not exist")
+}
+
+func (s SellerService) ApproveSellerApplication(id uint, admin domain.User) (*domain.SellerApplication, error) {
+	return s.Repo.ApproveSellerApplication(id, admin.ID, time.Now())
+}
+
+func (s SellerService) RejectSellerApplication(id uint, input dto.ReviewSellerApplicationRequest, admin domain.User) (*domain.SellerApplication, error) {
+	reason := strings.TrimSpace(input.Reason)
+	if len(reason) == 0 {
+		return nil, errors.New("please give a reason for the rejection")
+	}
+
+	return s.Repo.RejectSellerApplication(id, admin.ID, reason, time.Now())
+}
+
+func (s SellerService) validateApplication(e *domain.SellerApplication) error {
+	if len(e.FirstName) == 0 || len(e.LastName) == 0 {
+		return errors.New("first and last name are required")
+	}
+
+	if len(e.BusinessName) == 0 {
+		return errors.New("business name is required")
+	}
+
+	switch e.BusinessType {
+	case domain.BusinessIndividual:
+	case domain.BusinessCompany:
+		if len(e.RegistrationNumber) == 0 {
+			return errors.New("registration number is required for companies")
+		}
+	default:
+		return errors.New("business type must be individual or company")
+	}
+
+	if len(e.Address.Line1) == 0 || len(e.Address.City) == 0 {
+		return errors.New("business address line and city are required")
+	}
+
+	if len(e.Address.Country) != 2 {
+		return errors.New("country must be an ISO 3166-1 alpha-2 code")
+	}
+
+	if e.BankAccount == 0 || len(e.SwiftCode) == 0 {
+		return errors.New("bank account number and swift code are required")
+	}
+
+	if len(e.Currency) == 0 {
+		e.Currency = s.Config.Currency
+	}
+
+	rates, err := loadExchangeRates(s.Rates, s.Config.Currency)
+	if err != nil {
+		return err
+	}
+	if !rates.Supports(e.Currency) {
+		return fmt.Errorf("currency %v is not supported", e.Currency)
+	}
+
+	return nil
+}
+
+func (s SellerService) deleteDocuments(e *domain.SellerApplication) {
+	for _, doc := range e.Documents {
+		if err := s.Documents.Delete(doc.StorageKey); err != nil {
+			log.Printf("document %v could not be deleted: %v", doc.StorageKey, err)
+		}
+	}
+}
+
+// storeDocument validates an uploaded pdf or image and puts it into storage.
+// Documents are only handed out through the api, the key must not be guessable.
+func storeDocument(store storage.Storage, prefix string, docType string, file *multipart.FileHeader) (*domain.SellerDocument, error) {
+	if file.Size > maxDocumentSize {
+		return nil, errors.New("documents must be smaller than 10 MB")
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return nil, errors.New("unable to read document")
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxDocumentSize+1))
+	if err != nil {
+		return nil, errors.New("unable to read document")
+	}
+	if len(data) == 0 {
+		return nil, errors.New("document is empty")
+	}
+	if len(data) > maxDocumentSize {
+		return nil, errors.New("documents must be smaller than 10 MB")
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := documentExtensions[contentType]
+	if !ok {
+		return nil, errors.New("only pdf, jpeg and png documents are allowed")
+	}
+
+	name, err := helper.RandomHex(16)
+	if err != nil {
+		return nil, errors.New("unable to store document")
+	}
+
+	doc := &domain.SellerDocument{
+		Type:        docType,
+		FileName:    filepath.Base(file.Filename),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		StorageKey:  fmt.Sprintf("%v/%v%v", prefix, name, ext),
+	}
+
+	_, err = store.Put(doc.StorageKey, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"slices"
+	"strings"
+)
+
+type ShippingService struct {
+	Repo   repository.ShippingRepository
+	Rates  repository.ExchangeRateRepository
+	Auth   helper.Auth
+	Config config.AppConfig
+}
+
+func (s ShippingService) GetShippingProfiles(seller domain.User) ([]*domain.ShippingProfile, error) {
+	return s.Repo.FindSellerShippingProfiles(seller.ID)
+}
+
+func (s ShippingService) CreateShippingProfile(input dto.ShippingProfileRequest, seller domain.User) (*domain.ShippingProfile, error) {
+	profile := &domain.ShippingProfile{
+		UserId:       seller.ID,
+		Name:         strings.TrimSpace(input.Name),
+		Type:         input.Type,
+		Currency:     strings.ToUpper(input.Currency),
+		FlatRate:     input.FlatRate,
+		Tiers:        input.Tiers,
+		FreeOver:     input.FreeOver,
+		Countries:    input.Countries,
+		DeliveryDays: input.DeliveryDays,
+		Active:       input.Active == nil || *input.Active,
+	}
+
+	err := s.validateShippingProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repo.CreateShippingProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+func (s ShippingService) EditShippingProfile(id uint, input dto.EditShippingProfileRequest, seller domain.User) (*domain.ShippingProfile, error) {
+	profile, err := s.findOwnedProfile(id, seller)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(input.Name) > 0 {
+		profile.Name = strings.TrimSpace(input.Name)
+	}
+
+	if len(input.Type) > 0 {
+		profile.Type = input.Type
+	}
+
+	if currency := strings.ToUpper(input.Currency); len(currency) > 0 && currency != profile.Currency {
+		err = s.convertShippingProfile(profile, currency, input)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if input.FlatRate != nil {
+		profile.FlatRate = *input.FlatRate
+	}
+
+	if input.Tiers != nil {
+		profile.Tiers = input.Tiers
+	}
+
+	if input.FreeOver != nil {
+		profile.FreeOver = *input.FreeOver
+	}
+
+	if input.Countries != nil {
+		profile.Countries = input.Countries
+	}
+
+	if input.DeliveryDays > 0 {
+		profile.DeliveryDays = input.DeliveryDays
+	}
+
+	if input.Active != nil {
+		profile.Active = *input.Active
+	}
+
+	err = s.validateShippingProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.EditShippingProfile(profile)
+}
+
+// convertShippingProfile moves a profile to another currency, converting the
+// amounts the edit doesn't give again at the current exchange rates
+func (s ShippingService) convertShippingProfile(p *domain.ShippingProfile, to string, input dto.EditShippingProfileRequest) error {
+	rates, err := loadExchangeRates(s.Rates, s.Config.Currency)
+	if err != nil {
+		return err
+	}
+
+	convert := func(m domain.Money) (domain.Money, error) {
+		if len(m.Currency) == 0 {
+			m.Currency = p.Currency
+		}
+		return rates.Convert(m, to)
+	}
+
+	if input.FlatRate == nil {
+		p.FlatRate, err = convert(p.FlatRate)
+		if err != nil {
+			return err
+		}
+	}
+
+	if input.FreeOver == nil {
+		p.FreeOver, err = convert(p.FreeOver)
+		if err != nil {
+			return err
+		}
+	}
+
+	if input.Tiers == nil {
+		for i := range p.Tiers {
+			p.Tiers[i].Rate, err = convert(p.Tiers[i].Rate)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	p.Currency = to
+	return nil
+}
+
+func (s ShippingService) DeleteShippingProfile(id uint, seller domain.User) error {
+	profile, err := s.findOwnedProfile(id, seller)
+	if err != nil {
+		return err
+	}
+
+	return s.Repo.DeleteShippingProfile(profile.ID)
+}
+
+func (s ShippingService) findOwnedProfile(id uint, seller domain.User) (*domain.ShippingProfile, error) {
+	profile, err := s.Repo.FindShippingProfileById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if profile.UserId != seller.ID {
+		return nil, errors.New("you do not have manage rights of this shipping profile")
+	}
+
+	return profile, nil
+}
+
+// validateShippingProfile checks the profile can price a parcel and puts all
+// of its amounts in the profile currency
+func (s ShippingService) validateShippingProfile(p *domain.ShippingProfile) error {
+	if len(p.Name) == 0 {
+		return errors.New("shipping profile needs a name")
+	}
+
+	if len(p.Currency) == 0 {
+		p.Currency = s.Config.Currency
+	}
+
+	rates, err := loadExchangeRates(s.Rates, s.Config.Currency)
+	if err != nil {
+		return err
+	}
+	if !rates.Supports(p.Currency) {
+		return fmt.Errorf("currency %v is not supported", p.Currency)
+	}
+
+	p.FlatRate, err = priceIn(p.FlatRate, p.Currency)
+	if err != nil {
+		return err
+	}
+
+	p.FreeOver, err = priceIn(p.FreeOver, p.Currency)
+	if err != nil {
+		return err
+	}
+
+	switch p.Type {
+	case domain.ShippingFlat:
+		p.Tiers = nil
+	case domain.ShippingWeight:
+		if len(p.Tiers) == 0 {
+			return errors.New("weight based shipping needs at least one tier")
+		}
+		slices.SortFunc(p.Tiers, func(a, b domain.ShippingTier) int {
+			return int(a.UpTo) - int(b.UpTo)
+		})
+		for i := range p.Tiers {
+			if p.Tiers[i].UpTo == 0 || (i > 0 && p.Tiers[i].UpTo == p.Tiers[i-1].UpTo) {
+				return errors.New("every tier needs a different weight greater than 0")
+			}
+			p.Tiers[i].Rate, err = priceIn(p.Tiers[i].Rate, p.Currency)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.New("shipping type must be flat or weight")
+	}
+
+	for i, country := range p.Countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if len(country) != 2 {
+			return errors.New("countries must be ISO 3166-1 alpha-2 codes")
+		}
+		p.Countries[i] = country
+	}
+
+	if p.DeliveryDays < 0 {
+		return errors.New("delivery days can not be negative")
+	}
+
+	return nil
+}
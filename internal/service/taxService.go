@@ -0,0 +1,160 @@
+package service
+
+import (
+	"errors"
+	"go-ecommerce-app/config"
+	"go-ecommerce-app/internal/domain"
+	"go-ecommerce-app/internal/dto"
+	"go-ecommerce-app/internal/helper"
+	"go-ecommerce-app/internal/repository"
+	"strings"
+)
+
+type TaxService struct {
+	Repo   repository.TaxRepository
+	Auth   helper.Auth
+	Config config.AppConfig
+}
+
+func (s TaxService) GetTaxRules() ([]*domain.TaxRule, error) {
+	return s.Repo.FindTaxRules()
+}
+
+func (s TaxService) CreateTaxRule(input dto.CreateTaxRuleRequest) (*domain.TaxRule, error) {
+	rule := &domain.TaxRule{
+		Name:      strings.TrimSpace(input.Name),
+		Country:   strings.ToUpper(strings.TrimSpace(input.Country)),
+		Region:    strings.ToUpper(strings.TrimSpace(input.Region)),
+		TaxClass:  taxClassOrDefault(input.TaxClass),
+		Rate:      input.Rate,
+		Inclusive: input.Inclusive != nil && *input.Inclusive,
+		Active:    input.Active == nil || *input.Active,
+	}
+
+	err := validateTaxRule(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repo.CreateTaxRule(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (s TaxService) EditTaxRule(id uint, input dto.EditTaxRuleRequest) (*domain.TaxRule, error) {
+	rule, err := s.Repo.FindTaxRuleById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(input.Name) > 0 {
+		rule.Name = strings.TrimSpace(input.Name)
+	}
+
+	if len(input.Country) > 0 {
+		rule.Country = strings.ToUpper(strings.TrimSpace(input.Country))
+	}
+
+	// a region is cleared with "*"
+	if input.Region == "*" {
+		rule.Region = ""
+	} else if len(input.Region) > 0 {
+		rule.Region = strings.ToUpper(strings.TrimSpace(input.Region))
+	}
+
+	if len(input.TaxClass) > 0 {
+		rule.TaxClass = taxClassOrDefault(input.TaxClass)
+	}
+
+	if input.Rate != nil {
+		rule.Rate = *input.Rate
+	}
+
+	if input.Inclusive != nil {
+		rule.Inclusive = *input.Inclusive
+	}
+
+	if input.Active != nil {
+		rule.Active = *input.Active
+	}
+
+	err = validateTaxRule(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.EditTaxRule(rule)
+}
+
+func (s TaxService) DeleteTaxRule(id uint) error {
+	return s.Repo.DeleteTaxRule(id)
+}
+
+func taxClassOrDefault(taxClass string) string {
+	taxClass = strings.ToLower(strings.TrimSpace(taxClass))
+	if len(taxClass) == 0 {
+		return domain.TaxClassStandard
+	}
+	return taxClass
+}
+
+func validateTaxRule(r *domain.TaxRule) error {
+	if len(r.Name) == 0 {
+		return errors.New("tax rule needs a name")
+	}
+
+	if len(r.Country) != 2 {
+		return errors.New("country must be an ISO 3166-1 alpha-2 code")
+	}
+
+	if r.Rate < 0 || r.Rate > 100 {
+		return errors.New("rate must be between 0 and 100")
+	}
+
+	return nil
+}
+
+// applyTaxes adds the taxes of every order line for the shipping location,
+// exclusive taxes are added to the amount the buyer pays
+func applyTaxes(order *domain.Order, rules []*domain.TaxRule) error {
+	order.Tax = domain.NewMoney(0, order.Subtotal.Currency)
+	exclusive := domain.NewMoney(0, order.Subtotal.Currency)
+
+	for i := range order.Items {
+		item := &order.Items[i]
+
+		var matching []*domain.TaxRule
+		for _, r := range rules {
+			if r.Matches(order.Country, order.Region, item.TaxClass) {
+				matching = append(matching, r)
+			}
+		}
+
+		// taxes are charged on what the buyer pays for the line
+		lineAmount, err := item.Price.Mul(int64(item.Qty)).Sub(item.Discount)
+		if err != nil {
+			return err
+		}
+
+		item.Taxes = domain.CalculateTaxes(lineAmount, matching)
+		item.Tax = domain.NewMoney(0, lineAmount.Currency)
+		for _, tax := range item.Taxes {
+			item.Tax.Amount += tax.Amount.Amount
+			if !tax.Inclusive {
+				exclusive.Amount += tax.Amount.Amount
+			}
+		}
+		order.Tax.Amount += item.Tax.Amount
+	}
+
+	amount, err := order.Subtotal.Sub(order.Discount)
+	if err != nil {
+		return err
+	}
+
+	order.Amount, err = amount.Add(exclusive)
+	return err
+}
@@ -9,14 +9,25 @@ import (
 	"go-ecommerce-app/internal/helper"
 	"go-ecommerce-app/internal/repository"
 	"go-ecommerce-app/pkg/notification"
+	"go-ecommerce-app/pkg/payment"
 	"log"
+	"slices"
+	"strings"
 	"time"
 )
 
 type UserService struct {
-	Repo   repository.UserRepository
-	Auth   helper.Auth
-	Config config.AppConfig
+	Repo       repository.UserRepository
+	CRepo      repository.CatalogRepository
+	CouponRepo repository.CouponRepository
+	Rates      repository.ExchangeRateRepository
+	TaxRepo    repository.TaxRepository
+	ShipRepo   repository.ShippingRepository
+	Balance    repository.BalanceRepository
+	Invoices   InvoiceService
+	Wishlist   repository.WishlistRepository
+	Auth       helper.Auth
+	Config     config.AppConfig
 }
 
 func (s UserService) findUserByEmail(email string) (*domain.User, error) {
@@ -27,12 +38,14 @@ func (s UserService) findUserByEmail(email string) (*domain.User, error) {
 	return &user, err
 }
 
-func (s UserService) SignUp(input dto.UserSignUp) (string, error) {
+// SignUp creates the user and returns their token, a guest cart is taken
+// over with notices about items that could not be
+func (s UserService) SignUp(input dto.UserSignUp) (string, []string, error) {
 
 	// hashed password
 	hashedPassword, err := s.Auth.CreateHashedPasword(input.Password)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	user, err := s.Repo.CreateUser(domain.User{
@@ -45,22 +58,34 @@ func (s UserService) SignUp(input dto.UserSignUp) (string, error) {
 	userInfo := fmt.Sprintf("%v, %v, %v", user.ID, user.Email, user.UserType)
 	log.Println(userInfo)
 
-	return s.Auth.GenerateToken(user.ID, user.Email, user.UserType)
+	token, err := s.Auth.GenerateToken(user.ID, user.Email, user.UserType)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, s.mergeGuestCart(input.CartToken, user), nil
 }
 
-func (s UserService) Login(email string, password string) (string, error) {
+// Login returns the token of the user, a guest cart is taken over with
+// notices about items that could not be
+func (s UserService) Login(input dto.UserLogin) (string, []string, error) {
+
+	user, err := s.findUserByEmail(input.Email)
+	if err != nil {
+		return "", nil, errors.New("user doesn't exist with given email id")
+	}
 
-	user, err := s.findUserByEmail(email)
+	err = s.Auth.VerifyPassword(input.Password, user.Password)
 	if err != nil {
-		return "", errors.New("user doesn't exist with given email id")
+		return "", nil, err
 	}
 
-	err = s.Auth.VerifyPassword(password, user.Password)
+	token, err := s.Auth.GenerateToken(user.ID, user.Email, user.UserType)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return s.Auth.GenerateToken(user.ID, user.Email, user.UserType)
+	return token, s.mergeGuestCart(input.CartToken, *user), nil
 }
 
 func (s UserService) isVerifiedUser(id uint) bool {
@@ -150,61 +175,1099 @@ func (s UserService) UpdateProfile(id uint, input any) error {
 	return nil
 }
 
-func (s UserService) BecomeSeller(id uint, input dto.SellerInput) (string, error) {
-	// find exsiting user
-	user, _ := s.Repo.FindUserById(id)
+func (s UserService) FindCart(id uint) ([]*domain.Cart, error) {
+	cartItems, err := s.Repo.FindCartItems(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.refreshCartPrices(cartItems)
+	return cartItems, nil
+}
 
-	// if already seller return err
-	if user.UserType == domain.SELLER {
-		return "", errors.New("you have already joined the seller program")
+// refreshCartPrices shows the price in effect now, a sale may have started
+// or ended since the item was added
+func (s UserService) refreshCartPrices(cartItems []*domain.Cart) {
+	for _, item := range cartItems {
+		product, err := s.CRepo.FindProductById(int(item.ProductId))
+		if err != nil {
+			continue
+		}
+		price, _, _, err := lineDetails(product, item.VariantId)
+		if err == nil {
+			item.Price = price
+		}
 	}
+}
 
-	//update user
-	seller, err := s.Repo.UpdateUser(id, domain.User{
-		FirstName: input.FirstName,
-		LastName:  input.LastName,
-		Phone:     input.PhoneNumber,
-		UserType:  domain.SELLER,
-	})
+func (s UserService) CreateCart(input dto.CreateCartRequest, u domain.User) ([]*domain.Cart, error) {
+	err := s.saveCartItem(domain.Cart{UserId: u.ID}, input)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// generate token
-	token, err := s.Auth.GenerateToken(user.ID, user.Email, seller.UserType)
+	return s.FindCart(u.ID)
+}
+
+// GetGuestCart returns the cart of the cart token, a missing or invalid
+// token has an empty cart
+func (s UserService) GetGuestCart(token string) ([]*domain.Cart, error) {
+	guestId, err := s.Auth.VerifyCartToken(token)
+	if err != nil {
+		return []*domain.Cart{}, nil
+	}
+
+	cartItems, err := s.Repo.FindGuestCartItems(guestId)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// create bank account info
-	account := domain.BankAccount{
-		UserId:      seller.ID,
-		BankAccount: input.BankAccountNumber,
-		SwiftCode:   input.SwiftCode,
-		PaymentType: input.PaymentType,
+	s.refreshCartPrices(cartItems)
+	return cartItems, nil
+}
+
+// maxGuestCartLines is how many products a guest cart can hold, guests are
+// not tied to an account
+const maxGuestCartLines = 50
+
+// AddToGuestCart updates the cart of a guest. Guests without a valid cart
+// token get a new one, which is returned with the cart.
+func (s UserService) AddToGuestCart(token string, input dto.CreateCartRequest) (string, []*domain.Cart, error) {
+	guestId, err := s.Auth.VerifyCartToken(token)
+	if err != nil {
+		token, err = s.Auth.GenerateCartToken()
+		if err != nil {
+			return "", nil, err
+		}
+		guestId, _ = s.Auth.VerifyCartToken(token)
 	}
 
-	err = s.Repo.CreateBankAccount(account)
+	if cartItem, err := s.Repo.FindGuestCartItem(guestId, input.ProductId, input.VariantId); err != nil || cartItem.ID == 0 {
+		cartItems, err := s.Repo.FindGuestCartItems(guestId)
+		if err != nil {
+			return token, nil, err
+		}
+		if len(cartItems) >= maxGuestCartLines {
+			return token, nil, fmt.Errorf("a guest cart can hold up to %v products, please log in to add more", maxGuestCartLines)
+		}
+	}
 
-	return token, err
+	err = s.saveCartItem(domain.Cart{GuestId: guestId}, input)
+	if err != nil {
+		return token, nil, err
+	}
+
+	cart, err := s.GetGuestCart(token)
+	return token, cart, err
 }
 
-func (s UserService) FindCart(id uint) ([]interface{}, error) {
-	return nil, nil
+// mergeGuestCart moves the items of a guest cart into the user's cart after
+// logging in. Quantities of products in both carts add up, as far as the
+// stock goes. It returns what could not be moved as asked.
+func (s UserService) mergeGuestCart(token string, u domain.User) []string {
+	guestId, err := s.Auth.VerifyCartToken(token)
+	if err != nil {
+		return nil
+	}
+
+	guestItems, err := s.Repo.FindGuestCartItems(guestId)
+	if err != nil || len(guestItems) == 0 {
+		return nil
+	}
+
+	var notices []string
+	for _, item := range guestItems {
+		product, err := s.CRepo.FindProductById(int(item.ProductId))
+		if err != nil {
+			notices = append(notices, fmt.Sprintf("%v is no longer available", item.Name))
+			continue
+		}
+
+		_, stock, _, err := lineDetails(product, item.VariantId)
+		if err != nil {
+			notices = append(notices, err.Error())
+			continue
+		}
+
+		qty := item.Qty
+		if cartItem, err := s.Repo.FindCartItem(u.ID, item.ProductId, item.VariantId); err == nil && cartItem.ID > 0 {
+			qty += cartItem.Qty
+		}
+
+		if stock == 0 {
+			notices = append(notices, fmt.Sprintf("%v is out of stock", item.Name))
+			continue
+		}
+		if qty > stock {
+			notices = append(notices, fmt.Sprintf("only %v units of %v are available, the quantity was reduced", stock, item.Name))
+			qty = stock
+		}
+
+		err = s.saveCartItem(domain.Cart{UserId: u.ID}, dto.CreateCartRequest{
+			ProductId: item.ProductId,
+			VariantId: item.VariantId,
+			Qty:       qty,
+		})
+		if err != nil {
+			notices = append(notices, err.Error())
+		}
+	}
+
+	if err := s.Repo.DeleteGuestCart(guestId); err != nil {
+		log.Printf("error on deleting guest cart %v", err)
+	}
+
+	return notices
 }
 
-func (s UserService) CreateCart(input any, u domain.User) ([]interface{}, error) {
-	return nil, nil
+// saveCartItem sets the quantity of a product in the cart of a user, or of
+// a guest when owner has no user id. A quantity of 0 removes the product.
+func (s UserService) saveCartItem(owner domain.Cart, input dto.CreateCartRequest) error {
+	product, err := s.CRepo.FindProductById(int(input.ProductId))
+	if err != nil {
+		return errors.New("product does not exist")
+	}
+
+	price, stock, sku, err := lineDetails(product, input.VariantId)
+	if err != nil {
+		return err
+	}
+
+	// check if the cart already has this product
+	var cartItem domain.Cart
+	if owner.UserId > 0 {
+		cartItem, err = s.Repo.FindCartItem(owner.UserId, input.ProductId, input.VariantId)
+	} else {
+		cartItem, err = s.Repo.FindGuestCartItem(owner.GuestId, input.ProductId, input.VariantId)
+	}
+	if err == nil && cartItem.ID > 0 {
+		if input.Qty < 1 {
+			// remove the item from the cart
+			err = s.Repo.DeleteCartById(cartItem.ID)
+			if err != nil {
+				log.Printf("error on deleting cart item %v", err)
+				return errors.New("error on deleting cart item")
+			}
+		} else {
+			if input.Qty > stock {
+				return fmt.Errorf("only %v units of %v are available", stock, product.Name)
+			}
+
+			cartItem.Qty = input.Qty
+			cartItem.Price = price
+			err = s.Repo.UpdateCart(cartItem)
+			if err != nil {
+				return errors.New("error on updating cart item")
+			}
+		}
+	} else {
+		if input.Qty < 1 {
+			return errors.New("please provide a valid quantity")
+		}
+		if input.Qty > stock {
+			return fmt.Errorf("only %v units of %v are available", stock, product.Name)
+		}
+
+		err = s.Repo.CreateCart(domain.Cart{
+			UserId:    owner.UserId,
+			GuestId:   owner.GuestId,
+			ProductId: product.ID,
+			VariantId: input.VariantId,
+			Sku:       sku,
+			Name:      product.Name,
+			ImageUrl:  product.ImageUrl,
+			SellerId:  uint(product.UserId),
+			Price:     price,
+			Qty:       input.Qty,
+		})
+		if err != nil {
+			return errors.New("error on creating cart item")
+		}
+	}
+
+	return nil
 }
 
-func (s UserService) CreateOrder(u domain.User) (int, error) {
-	return 0, nil
+func (s UserService) GetWishlist(u domain.User) ([]*domain.WishlistItem, error) {
+	items, err := s.Wishlist.FindWishlist(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// products removed from the catalog stay listed without details
+	for _, item := range items {
+		if product, err := s.CRepo.FindProductById(int(item.ProductId)); err == nil {
+			item.Product = product
+		}
+	}
+
+	return items, nil
 }
 
-func (s UserService) GetOrders(u domain.User) ([]interface{}, error) {
-	return nil, nil
+func (s UserService) AddToWishlist(input dto.WishlistRequest, u domain.User) ([]*domain.WishlistItem, error) {
+	return s.addToWishlist(input, u, true)
 }
 
-func (s UserService) GetOrderById(id uint, uId uint) (interface{}, error) {
-	return nil, nil
+// addToWishlist saves the product on the wishlist. The notification choices
+// of an item already there are kept unless updateNotify is set.
+func (s UserService) addToWishlist(input dto.WishlistRequest, u domain.User, updateNotify bool) ([]*domain.WishlistItem, error) {
+	product, err := s.CRepo.FindProductById(int(input.ProductId))
+	if err != nil {
+		return nil, errors.New("product does not exist")
+	}
+
+	price, stock, _, err := lineDetails(product, input.VariantId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Wishlist.SaveWishlistItem(&domain.WishlistItem{
+		UserId:          u.ID,
+		ProductId:       product.ID,
+		VariantId:       input.VariantId,
+		NotifyPriceDrop: input.NotifyPriceDrop,
+		NotifyInStock:   input.NotifyInStock,
+		LastPrice:       price,
+		WasAvailable:    stock > 0,
+	}, updateNotify)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetWishlist(u)
+}
+
+func (s UserService) RemoveFromWishlist(id uint, u domain.User) ([]*domain.WishlistItem, error) {
+	item, err := s.Wishlist.FindWishlistItemById(id, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Wishlist.DeleteWishlistItem(item.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetWishlist(u)
+}
+
+// MoveToCart adds units of the wishlist item to the cart and takes it off
+// the wishlist
+func (s UserService) MoveToCart(id uint, input dto.MoveToCartRequest, u domain.User) ([]*domain.Cart, error) {
+	item, err := s.Wishlist.FindWishlistItemById(id, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	qty := max(input.Qty, 1)
+	if cartItem, err := s.Repo.FindCartItem(u.ID, item.ProductId, item.VariantId); err == nil && cartItem.ID > 0 {
+		qty += cartItem.Qty
+	}
+
+	cart, err := s.CreateCart(dto.CreateCartRequest{
+		ProductId: item.ProductId,
+		VariantId: item.VariantId,
+		Qty:       qty,
+	}, u)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Wishlist.DeleteWishlistItem(item.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return cart, nil
+}
+
+// SaveForLater moves a cart line to the wishlist
+func (s UserService) SaveForLater(input dto.SaveForLaterRequest, u domain.User) ([]*domain.WishlistItem, error) {
+	cartItem, err := s.Repo.FindCartItem(u.ID, input.ProductId, input.VariantId)
+	if err != nil || cartItem.ID == 0 {
+		return nil, errors.New("item is not in the cart")
+	}
+
+	items, err := s.addToWishlist(dto.WishlistRequest{
+		ProductId: input.ProductId,
+		VariantId: input.VariantId,
+	}, u, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repo.DeleteCartById(cartItem.ID)
+	if err != nil {
+		return nil, errors.New("error on deleting cart item")
+	}
+
+	return items, nil
+}
+
+// NotifyWishlists tells buyers who asked for it that a wishlisted product
+// got cheaper or is back in stock
+func (s UserService) NotifyWishlists() (int, error) {
+	items, err := s.Wishlist.FindWatchedWishlistItems()
+	if err != nil {
+		return 0, err
+	}
+
+	notified := 0
+
+	for _, item := range items {
+		product := item.Product
+		if product == nil {
+			continue
+		}
+
+		price, stock, _, err := lineDetails(product, item.VariantId)
+		if err != nil {
+			// not on sale at the moment
+			continue
+		}
+
+		var messages []string
+		if item.NotifyPriceDrop && price.SameCurrency(item.LastPrice) && price.Amount < item.LastPrice.Amount {
+			messages = append(messages, fmt.Sprintf("Price drop: %v is now %v, was %v", product.Name, price, item.LastPrice))
+		}
+		if item.NotifyInStock && stock > 0 && !item.WasAvailable {
+			messages = append(messages, fmt.Sprintf("Back in stock: %v", product.Name))
+		}
+
+		if price == item.LastPrice && (stock > 0) == item.WasAvailable {
+			continue
+		}
+
+		item.LastPrice = price
+		item.WasAvailable = stock > 0
+		err = s.Wishlist.UpdateWishlistWatch(item, messages)
+		if err != nil {
+			return notified, err
+		}
+		notified += len(messages)
+	}
+
+	return notified, nil
+}
+
+func (s UserService) CreateOrder(input dto.CreateOrderRequest, u domain.User) (uint, error) {
+	address, err := s.deliveryAddress(input.AddressId, u)
+	if err != nil {
+		return 0, err
+	}
+
+	order, err := s.buildOrder(u)
+	if err != nil {
+		return 0, err
+	}
+
+	// stock is reserved until the order is paid or the window passes
+	order.Status = domain.OrderPending
+	order.ExpiresAt = time.Now().Add(s.Config.ReservationWindow)
+
+	var redemption *domain.CouponRedemption
+	if cartCoupon, err := s.CouponRepo.FindCartCoupon(u.ID); err == nil {
+		coupon, err := s.CouponRepo.FindCouponById(cartCoupon.CouponId)
+		if err == nil {
+			err = s.applyCoupon(order, coupon, u)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("coupon can not be applied: %v, please remove it to continue", err)
+		}
+
+		redemption = &domain.CouponRedemption{
+			CouponId: coupon.ID,
+			Amount:   order.Discount,
+		}
+	}
+
+	err = s.applyOrderTaxes(order, dto.ShippingLocation{Country: address.Country, Region: address.Region})
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.applyShipping(order, address, input.ShippingProfileIds)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.Repo.CreateOrder(order, redemption)
+	if err != nil {
+		return 0, err
+	}
+
+	return order.ID, nil
+}
+
+// GetCartSummary totals the cart, taxes are included when the location is known
+func (s UserService) GetCartSummary(location dto.ShippingLocation, u domain.User) (*dto.CartSummary, error) {
+	summary := &dto.CartSummary{}
+
+	order, err := s.buildOrder(u)
+	if err != nil {
+		// an empty cart has an empty summary
+		return summary, nil
+	}
+
+	if cartCoupon, err := s.CouponRepo.FindCartCoupon(u.ID); err == nil {
+		coupon, err := s.CouponRepo.FindCouponById(cartCoupon.CouponId)
+		if err == nil {
+			summary.CouponCode = coupon.Code
+			err = s.applyCoupon(order, coupon, u)
+		}
+		if err != nil {
+			summary.CouponError = err.Error()
+		}
+	}
+
+	if len(location.Country) > 0 {
+		err = s.applyOrderTaxes(order, location)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	summary.Subtotal = order.Subtotal
+	summary.Discount = order.Discount
+	summary.Tax = order.Tax
+	summary.Total = order.Amount
+
+	return summary, nil
+}
+
+func (s UserService) ApplyCoupon(input dto.ApplyCouponRequest, location dto.ShippingLocation, u domain.User) (*dto.CartSummary, error) {
+	coupon, err := s.CouponRepo.FindCouponByCode(strings.ToUpper(strings.TrimSpace(input.Code)))
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.buildOrder(u)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.applyCoupon(order, coupon, u)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.CouponRepo.SetCartCoupon(u.ID, coupon.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetCartSummary(location, u)
+}
+
+func (s UserService) RemoveCoupon(location dto.ShippingLocation, u domain.User) (*dto.CartSummary, error) {
+	err := s.CouponRepo.DeleteCartCoupon(u.ID)
+	if err != nil {
+		return nil, errors.New("failed to remove coupon")
+	}
+
+	return s.GetCartSummary(location, u)
+}
+
+// applyOrderTaxes charges the taxes of the country and region the order is
+// shipped to
+func (s UserService) applyOrderTaxes(order *domain.Order, location dto.ShippingLocation) error {
+	order.Country = strings.ToUpper(strings.TrimSpace(location.Country))
+	order.Region = strings.ToUpper(strings.TrimSpace(location.Region))
+
+	if len(order.Country) != 2 {
+		return errors.New("please provide the country the order is shipped to")
+	}
+
+	rules, err := s.TaxRepo.FindCountryTaxRules(order.Country)
+	if err != nil {
+		return err
+	}
+
+	return applyTaxes(order, rules)
+}
+
+func (s UserService) CreateAddress(input dto.AddressRequest, u domain.User) (*domain.Address, error) {
+	address := &domain.Address{
+		UserId: u.ID,
+		PostalAddress: domain.PostalAddress{
+			Name:     strings.TrimSpace(input.Name),
+			Line1:    strings.TrimSpace(input.Line1),
+			Line2:    strings.TrimSpace(input.Line2),
+			City:     strings.TrimSpace(input.City),
+			PostCode: strings.TrimSpace(input.PostCode),
+			Region:   strings.ToUpper(strings.TrimSpace(input.Region)),
+			Country:  strings.ToUpper(strings.TrimSpace(input.Country)),
+			Phone:    strings.TrimSpace(input.Phone),
+		},
+	}
+
+	if len(address.Name) == 0 || len(address.Line1) == 0 || len(address.City) == 0 {
+		return nil, errors.New("name, address line and city are required")
+	}
+
+	if len(address.Country) != 2 {
+		return nil, errors.New("country must be an ISO 3166-1 alpha-2 code")
+	}
+
+	err := s.Repo.CreateAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return address, nil
+}
+
+func (s UserService) GetAddresses(u domain.User) ([]*domain.Address, error) {
+	return s.Repo.FindAddresses(u.ID)
+}
+
+func (s UserService) DeleteAddress(id uint, u domain.User) error {
+	return s.Repo.DeleteAddress(id, u.ID)
+}
+
+// GetShippingRates lists the shipping methods of every seller in the cart
+// with their price for the delivery address
+func (s UserService) GetShippingRates(addressId uint, u domain.User) ([]*dto.SellerShippingRates, error) {
+	address, err := s.Repo.FindAddressById(addressId, u.ID)
+	if err != nil {
+		return nil, errors.New("please choose a delivery address")
+	}
+
+	order, err := s.buildOrder(u)
+	if err != nil {
+		return nil, err
+	}
+
+	// free shipping thresholds count the discount of a valid coupon
+	if cartCoupon, err := s.CouponRepo.FindCartCoupon(u.ID); err == nil {
+		if coupon, err := s.CouponRepo.FindCouponById(cartCoupon.CouponId); err == nil {
+			_ = s.applyCoupon(order, coupon, u)
+		}
+	}
+
+	rates, err := loadExchangeRates(s.Rates, s.Config.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	parcels := sellerParcels(order)
+	profiles, err := s.ShipRepo.FindShippingProfilesBySellers(parcelSellers(parcels))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*dto.SellerShippingRates
+	for _, parcel := range parcels {
+		sellerRates := &dto.SellerShippingRates{
+			SellerId: parcel.SellerId,
+			Weight:   parcel.Weight,
+			Subtotal: parcel.Subtotal,
+			Options:  []dto.ShippingOption{},
+		}
+
+		hasProfiles := false
+		for _, p := range profiles {
+			if p.UserId != parcel.SellerId {
+				continue
+			}
+			hasProfiles = true
+
+			price, err := quoteShipping(p, address.Country, parcel, rates)
+			if err != nil {
+				continue
+			}
+			sellerRates.Options = append(sellerRates.Options, dto.ShippingOption{
+				ProfileId:    p.ID,
+				Name:         p.Name,
+				DeliveryDays: p.DeliveryDays,
+				Price:        price,
+			})
+		}
+
+		if !hasProfiles {
+			sellerRates.Options = append(sellerRates.Options, dto.ShippingOption{
+				Name:  freeShipping,
+				Price: domain.NewMoney(0, parcel.Subtotal.Currency),
+			})
+		}
+
+		result = append(result, sellerRates)
+	}
+
+	return result, nil
+}
+
+// sellers without shipping profiles ship for free
+const freeShipping = "Free shipping"
+
+type parcel struct {
+	SellerId uint
+	Weight   uint
+	Subtotal domain.Money
+}
+
+// sellerParcels groups the order items by seller, in order of appearance
+func sellerParcels(order *domain.Order) []*parcel {
+	var parcels []*parcel
+	bySeller := make(map[uint]*parcel)
+
+	for _, item := range order.Items {
+		p, ok := bySeller[item.SellerId]
+		if !ok {
+			p = &parcel{SellerId: item.SellerId, Subtotal: domain.NewMoney(0, item.Price.Currency)}
+			bySeller[item.SellerId] = p
+			parcels = append(parcels, p)
+		}
+		p.Weight += item.Weight * item.Qty
+		p.Subtotal.Amount += item.Price.Amount*int64(item.Qty) - item.Discount.Amount
+	}
+
+	return parcels
+}
+
+func parcelSellers(parcels []*parcel) []uint {
+	ids := make([]uint, 0, len(parcels))
+	for _, p := range parcels {
+		ids = append(ids, p.SellerId)
+	}
+	return ids
+}
+
+// quoteShipping prices the parcel with the profile, in the parcel currency
+func quoteShipping(profile *domain.ShippingProfile, country string, p *parcel, rates domain.ExchangeRates) (domain.Money, error) {
+	subtotal, err := rates.Convert(p.Subtotal, profile.Currency)
+	if err != nil {
+		return domain.Money{}, err
+	}
+
+	price, err := profile.Quote(country, p.Weight, subtotal)
+	if err != nil {
+		return domain.Money{}, err
+	}
+
+	return rates.Convert(price, p.Subtotal.Currency)
+}
+
+// applyShipping splits the order into one shipment per seller, shipped with
+// the method chosen for it. Taxes must be applied first, they are part of
+// the shipment totals.
+func (s UserService) applyShipping(order *domain.Order, address *domain.Address, profileIds []uint) error {
+	rates, err := loadExchangeRates(s.Rates, s.Config.Currency)
+	if err != nil {
+		return err
+	}
+
+	parcels := sellerParcels(order)
+	profiles, err := s.ShipRepo.FindShippingProfilesBySellers(parcelSellers(parcels))
+	if err != nil {
+		return err
+	}
+
+	order.AddressId = address.ID
+	order.ShipTo = address.PostalAddress
+	order.ShippingTotal = domain.NewMoney(0, order.Subtotal.Currency)
+
+	for _, parcel := range parcels {
+		shipment := domain.NewShipment(parcel.SellerId, order.Items, order.Subtotal.Currency)
+		shipment.ShippingMethod = freeShipping
+		price := domain.NewMoney(0, parcel.Subtotal.Currency)
+
+		var sellerProfiles []*domain.ShippingProfile
+		var chosen *domain.ShippingProfile
+		for _, p := range profiles {
+			if p.UserId == parcel.SellerId {
+				sellerProfiles = append(sellerProfiles, p)
+				if slices.Contains(profileIds, p.ID) {
+					chosen = p
+				}
+			}
+		}
+
+		// a seller with a single method needs no choice
+		if chosen == nil && len(sellerProfiles) == 1 {
+			chosen = sellerProfiles[0]
+		}
+
+		if len(sellerProfiles) > 0 {
+			if chosen == nil {
+				return fmt.Errorf("please choose a shipping method for the items of seller %v", parcel.SellerId)
+			}
+
+			price, err = quoteShipping(chosen, address.Country, parcel, rates)
+			if err != nil {
+				return err
+			}
+			shipment.ShippingProfileId = chosen.ID
+			shipment.ShippingMethod = chosen.Name
+			shipment.DeliveryDays = chosen.DeliveryDays
+		}
+
+		if err := shipment.SetShipping(price); err != nil {
+			return err
+		}
+
+		// the seller is paid in their own currency at the rate of checkout
+		shipment.Payout, err = rates.Convert(shipment.Amount, s.sellerCurrency(parcel.SellerId))
+		if err != nil {
+			return err
+		}
+
+		order.Shipments = append(order.Shipments, shipment)
+		order.ShippingTotal.Amount += price.Amount
+	}
+
+	order.Amount, err = order.Amount.Add(order.ShippingTotal)
+	return err
+}
+
+// deliveryAddress is the chosen address of the buyer, or their only one
+// when none was chosen
+func (s UserService) deliveryAddress(id uint, u domain.User) (*domain.Address, error) {
+	if id == 0 {
+		addresses, err := s.Repo.FindAddresses(u.ID)
+		if err == nil && len(addresses) == 1 {
+			return addresses[0], nil
+		}
+	}
+
+	address, err := s.Repo.FindAddressById(id, u.ID)
+	if err != nil {
+		return nil, errors.New("please choose a delivery address")
+	}
+
+	return address, nil
+}
+
+// buildOrder turns the cart into order items priced with the current catalog
+func (s UserService) buildOrder(u domain.User) (*domain.Order, error) {
+	cartItems, err := s.Repo.FindCartItems(u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cartItems) == 0 {
+		return nil, errors.New("cart is empty, cannot create the order")
+	}
+
+	rates, err := loadExchangeRates(s.Rates, s.Config.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// the order is paid in the currency its sellers are paid in, or in the
+	// store currency when they are paid in different ones. Shipments are
+	// converted to the seller currency on their own.
+	currency := ""
+	for _, item := range cartItems {
+		sellerCurrency := s.sellerCurrency(item.SellerId)
+		if len(currency) > 0 && currency != sellerCurrency {
+			currency = s.Config.Currency
+			break
+		}
+		currency = sellerCurrency
+	}
+
+	order := &domain.Order{UserId: u.ID, Subtotal: domain.NewMoney(0, currency)}
+
+	for _, item := range cartItems {
+		product, err := s.CRepo.FindProductById(int(item.ProductId))
+		if err != nil {
+			return nil, fmt.Errorf("%v is no longer available", item.Name)
+		}
+
+		listPrice, _, sku, err := lineDetails(product, item.VariantId)
+		if err != nil {
+			return nil, err
+		}
+
+		price, err := rates.Convert(listPrice, currency)
+		if err != nil {
+			return nil, err
+		}
+
+		order.Subtotal.Amount += price.Amount * int64(item.Qty)
+		order.Items = append(order.Items, domain.OrderItem{
+			ProductId:  item.ProductId,
+			VariantId:  item.VariantId,
+			Sku:        sku,
+			Name:       item.Name,
+			ImageUrl:   item.ImageUrl,
+			SellerId:   item.SellerId,
+			CategoryId: product.CategoryId,
+			TaxClass:   product.TaxClass,
+			Weight:     product.ShippingWeight(),
+			Price:      price,
+			Qty:        item.Qty,
+		})
+	}
+
+	order.Discount = domain.NewMoney(0, order.Subtotal.Currency)
+	order.Amount = order.Subtotal
+
+	return order, nil
+}
+
+// sellerCurrency is the currency the seller is paid out in
+func (s UserService) sellerCurrency(sellerId uint) string {
+	seller, err := s.Repo.FindUserById(sellerId)
+	if err != nil || len(seller.Currency) == 0 {
+		return s.Config.Currency
+	}
+	return seller.Currency
+}
+
+// applyCoupon checks the coupon can be used by the user on this order and
+// spreads its discount over the eligible items
+func (s UserService) applyCoupon(order *domain.Order, coupon *domain.Coupon, u domain.User) error {
+	err := coupon.IsUsable(time.Now())
+	if err != nil {
+		return err
+	}
+
+	total, byUser, err := s.CouponRepo.CountRedemptions(coupon.ID, u.ID)
+	if err != nil {
+		return err
+	}
+	if coupon.UsageLimit > 0 && total >= int64(coupon.UsageLimit) {
+		return errors.New("coupon usage limit has been reached")
+	}
+	if coupon.PerUserLimit > 0 && byUser >= int64(coupon.PerUserLimit) {
+		return errors.New("you have already used this coupon")
+	}
+
+	// coupon amounts are set in the store currency
+	if order.Subtotal.Currency != s.Config.Currency {
+		rates, err := loadExchangeRates(s.Rates, s.Config.Currency)
+		if err != nil {
+			return err
+		}
+
+		converted := *coupon
+		if converted.Amount, err = rates.Convert(coupon.Amount, order.Subtotal.Currency); err != nil {
+			return err
+		}
+		if converted.MinOrder, err = rates.Convert(coupon.MinOrder, order.Subtotal.Currency); err != nil {
+			return err
+		}
+		coupon = &converted
+	}
+
+	eligible := domain.NewMoney(0, order.Subtotal.Currency)
+	var eligibleItems []int
+	for i, item := range order.Items {
+		if coupon.AppliesTo(item.CategoryId, item.SellerId) {
+			// all items share the currency of the subtotal
+			eligible.Amount += item.Price.Amount * int64(item.Qty)
+			eligibleItems = append(eligibleItems, i)
+		}
+	}
+
+	discount, err := coupon.Discount(eligible)
+	if err != nil {
+		return err
+	}
+
+	// the last item takes the rounding remainder
+	remaining := discount
+	for n, i := range eligibleItems {
+		item := &order.Items[i]
+		share := discount.Share(item.Price.Amount*int64(item.Qty), eligible.Amount)
+		if n == len(eligibleItems)-1 || share.Amount > remaining.Amount {
+			share = remaining
+		}
+		item.Discount = share
+		remaining.Amount -= share.Amount
+	}
+
+	order.Discount = discount
+	order.CouponCode = coupon.Code
+	order.Amount, err = order.Subtotal.Sub(discount)
+
+	return err
+}
+
+func (s UserService) GetOrders(u domain.User) ([]*domain.Order, error) {
+	orders, err := s.Repo.FindOrders(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (s UserService) GetOrderById(id uint, uId uint) (*domain.Order, error) {
+	order, err := s.Repo.FindOrderById(id, uId)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s UserService) GetSellerOrders(seller domain.User) ([]*domain.Order, error) {
+	return s.Repo.FindSellerOrders(seller.ID)
+}
+
+// UpdateOrderStatus moves the seller's shipment of the order on, the order
+// itself follows once every seller got that far
+func (s UserService) UpdateOrderStatus(id uint, input dto.UpdateOrderStatusRequest, seller domain.User) (*domain.Order, error) {
+	order, err := s.Repo.FindSellerOrderById(id, seller.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(order.Shipments) == 0 {
+		return nil, errors.New("order does not exist")
+	}
+
+	shipment := order.Shipments[0]
+	if !shipment.CanMoveTo(input.Status) {
+		return nil, fmt.Errorf("shipment can not be moved from %v to %v", shipment.Status, input.Status)
+	}
+
+	err = s.Repo.UpdateShipmentStatus(shipment.ID, input.Status, strings.TrimSpace(input.TrackingNumber), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repo.FindSellerOrderById(id, seller.ID)
+}
+
+// GetSellerBalance returns what the seller is owed, per currency, and a
+// page of the sales and refunds that make it up
+func (s UserService) GetSellerBalance(page dto.PaginationRequest, seller domain.User) ([]domain.Money, []*domain.BalanceEntry, *dto.PaginationResponse, error) {
+	page.Normalize()
+
+	balance, err := s.Balance.FindSellerBalance(seller.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	entries, total, err := s.Balance.FindBalanceEntries(seller.ID, page.Limit, page.Offset())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return balance, entries, &dto.PaginationResponse{
+		Page:  page.Page,
+		Limit: page.Limit,
+		Total: total,
+	}, nil
+}
+
+func (s UserService) PayOrder(id uint, input dto.PaymentRequest, u domain.User) (*domain.Order, error) {
+	order, err := s.Repo.FindOrderById(id, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status != domain.OrderPending {
+		return nil, fmt.Errorf("order is %v, it can not be paid", order.Status)
+	}
+
+	if !time.Now().Before(order.ExpiresAt) {
+		return nil, errors.New("order has expired, please checkout again")
+	}
+
+	// orders fully covered by a coupon have nothing to charge
+	paymentClient, err := payment.NewPaymentClient(s.Config)
+	if err != nil {
+		return nil, err
+	}
+	transactionId := ""
+	if order.Amount.IsPositive() {
+		transactionId, err = paymentClient.Charge(order.Amount.Amount, order.Amount.Currency, fmt.Sprintf("order_%v", order.ID), input.PaymentToken)
+		if err != nil {
+			return nil, fmt.Errorf("payment failed: %v", err)
+		}
+	}
+
+	err = s.Repo.ConfirmOrderPayment(order.ID, transactionId, time.Now())
+	if err != nil {
+		if len(transactionId) == 0 {
+			return nil, errors.New("order could not be confirmed")
+		}
+		// the reservation ran out while charging, give the money back
+		if _, refundErr := paymentClient.Refund(transactionId, order.Amount.Amount, order.Amount.Currency, transactionId); refundErr != nil {
+			log.Printf("refund of %v for order %v failed: %v", transactionId, order.ID, refundErr)
+		}
+		return nil, errors.New("order could not be confirmed, the payment has been refunded")
+	}
+
+	s.Invoices.StoreOrderInvoices(order.ID)
+
+	return s.Repo.FindOrderById(order.ID, u.ID)
+}
+
+// CancelOrder cancels an order none of whose shipments has been sent and
+// refunds what was paid for it. Cancelling again retries a failed refund.
+func (s UserService) CancelOrder(id uint, u domain.User) (*domain.Order, error) {
+	order, err := s.Repo.FindOrderById(id, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status != domain.OrderCancelled {
+		order, err = s.Repo.CancelOrder(id, u.ID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		// the invoices now show their credit notes
+		if order.PaidAt != nil {
+			s.Invoices.StoreOrderInvoices(order.ID)
+		}
+	}
+
+	// orders fully covered by a coupon were never charged. The refund is
+	// claimed first, a claim left by a failed refund is retried with the
+	// same idempotency key.
+	refundable := len(order.RefundId) == 0 || order.RefundId == domain.RefundPending
+	if order.PaidAt != nil && order.Amount.IsPositive() && refundable {
+		paymentClient, err := payment.NewPaymentClient(s.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(order.RefundId) == 0 {
+			err = s.Repo.ClaimOrderRefund(order.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		refundId, err := paymentClient.Refund(order.TransactionId, order.Amount.Amount, order.Amount.Currency, fmt.Sprintf("order_%v", order.ID))
+		if err != nil {
+			log.Printf("refund of cancelled order %v failed: %v", order.ID, err)
+			return nil, errors.New("order was cancelled but the refund failed, please cancel again to retry it")
+		}
+
+		err = s.Repo.SetOrderRefund(order.ID, refundId)
+		if err != nil {
+			log.Printf("refund %v of cancelled order %v was made but not recorded: %v", refundId, order.ID, err)
+			return nil, err
+		}
+	}
+
+	return s.Repo.FindOrderById(id, u.ID)
+}
+
+// lineDetails resolves the price, available stock and sku of a product or
+// one of its variants. Products with variants can only be bought by variant.
+func lineDetails(product *domain.Product, variantId uint) (domain.Money, uint, string, error) {
+	if !product.IsVisible() {
+		return domain.Money{}, 0, "", fmt.Errorf("%v is no longer available", product.Name)
+	}
+
+	if len(product.Variants) == 0 {
+		if variantId > 0 {
+			return domain.Money{}, 0, "", errors.New("product does not have variants")
+		}
+		return product.PriceAt(time.Now()), product.Available, "", nil
+	}
+
+	variant, ok := product.FindVariant(variantId)
+	if !ok {
+		return domain.Money{}, 0, "", errors.New("please select a valid variant of this product")
+	}
+
+	return variant.PriceFor(*product, time.Now()), variant.Available, variant.Sku, nil
 }
@@ -0,0 +1,65 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/config"
+	"log"
+	"time"
+)
+
+type PaymentClient interface {
+	// Charge takes the amount, in minor units of currency, from the payment
+	// method behind token and returns the gateway transaction id
+	Charge(amount int64, currency string, reference string, token string) (string, error)
+	// Refund returns amount of a previous charge and returns the refund id.
+	// Calls with the same idempotency key make one refund.
+	Refund(transactionId string, amount int64, currency string, idempotencyKey string) (string, error)
+}
+
+type sandboxClient struct {
+	config config.AppConfig
+}
+
+// NewPaymentClient returns the configured payment gateway. No provider is
+// integrated yet, the sandbox gateway approves every payment and is only
+// used when PAYMENT_SANDBOX is set.
+func NewPaymentClient(config config.AppConfig) (PaymentClient, error) {
+	if !config.PaymentSandbox {
+		return nil, errors.New("no payment gateway is configured")
+	}
+
+	return &sandboxClient{
+		config: config,
+	}, nil
+}
+
+func (c sandboxClient) Charge(amount int64, currency string, reference string, token string) (string, error) {
+	if len(token) == 0 {
+		return "", errors.New("payment token is required")
+	}
+
+	if amount <= 0 {
+		return "", errors.New("payment amount must be greater than 0")
+	}
+
+	transactionId := fmt.Sprintf("sandbox_ch_%v_%v", reference, time.Now().UnixNano())
+	log.Printf("sandbox payment %v charged %v %v", transactionId, amount, currency)
+
+	return transactionId, nil
+}
+
+func (c sandboxClient) Refund(transactionId string, amount int64, currency string, idempotencyKey string) (string, error) {
+	if len(transactionId) == 0 {
+		return "", errors.New("transaction id is required")
+	}
+
+	if len(idempotencyKey) == 0 {
+		return "", errors.New("idempotency key is required")
+	}
+
+	refundId := fmt.Sprintf("sandbox_re_%v", idempotencyKey)
+	log.Printf("sandbox refund %v of %v %v for %v", refundId, amount, currency, transactionId)
+
+	return refundId, nil
+}
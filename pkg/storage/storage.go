@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"go-ecommerce-app/config"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage keeps uploaded files under a key and exposes them by URL
+type Storage interface {
+	Put(key string, r io.Reader) (string, error)
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	URL(key string) string // empty for private storage
+}
+
+type localStorage struct {
+	dir     string
+	baseUrl string
+}
+
+// NewLocalStorage stores files on the local filesystem, they are served by the
+// api under config.StorageBaseUrl
+func NewLocalStorage(config config.AppConfig) Storage {
+	return &localStorage{
+		dir:     config.StorageDir,
+		baseUrl: strings.TrimSuffix(config.StorageBaseUrl, "/"),
+	}
+}
+
+// NewPrivateStorage stores files on the local filesystem outside the served
+// directory, they can only be read through Get
+func NewPrivateStorage(config config.AppConfig) Storage {
+	return &localStorage{
+		dir: config.PrivateStorageDir,
+	}
+}
+
+func (s localStorage) Put(key string, r io.Reader) (string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		log.Println("storage_err:", err)
+		return "", errors.New("failed to store file")
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		log.Println("storage_err:", err)
+		return "", errors.New("failed to store file")
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	if err != nil {
+		log.Println("storage_err:", err)
+		return "", errors.New("failed to store file")
+	}
+
+	return s.URL(key), nil
+}
+
+func (s localStorage) Get(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Println("storage_err:", err)
+		return nil, errors.New("file does not exist")
+	}
+
+	return f, nil
+}
+
+func (s localStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		log.Println("storage_err:", err)
+		return errors.New("failed to delete file")
+	}
+
+	return nil
+}
+
+func (s localStorage) URL(key string) string {
+	if len(s.baseUrl) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v/%v", s.baseUrl, key)
+}
+
+// path resolves a key inside the storage directory and rejects keys that
+// would escape it
+func (s localStorage) path(key string) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	rel, err := filepath.Rel(s.dir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", errors.New("invalid storage key")
+	}
+	return path, nil
+}